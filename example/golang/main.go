@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -12,13 +13,16 @@ import (
 	kittenipc "github.com/egor3f/kitten-ipc/lib/golang"
 )
 
+// kittenipc:error
+var ErrZeroDivision = kittenipc.NewError("ZeroDivision", "zero division")
+
 // kittenipc:api
 type GoIpcApi struct {
 }
 
-func (api GoIpcApi) Div(a int, b int) (int, error) {
+func (api GoIpcApi) Div(ctx context.Context, a int, b int) (int, error) {
 	if b == 0 {
-		return 0, fmt.Errorf("zero division")
+		return 0, ErrZeroDivision
 	}
 	return a / b, nil
 }
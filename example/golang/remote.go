@@ -3,6 +3,7 @@
 package main
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 
@@ -11,6 +12,8 @@ import (
 
 var _ = base64.StdEncoding
 
+var ZeroDivisionError = kittenipc.NewError("ZeroDivision", "")
+
 type TsIpcApi struct {
 	Ipc kittenipc.IpcCommon
 }
@@ -20,7 +23,15 @@ func (t *TsIpcApi) Div(
 ) (
 	int, error,
 ) {
-	results, err := t.Ipc.Call("TsIpcApi.Div", a, b)
+	return t.DivContext(context.Background(), a, b)
+}
+
+func (t *TsIpcApi) DivContext(
+	ctx context.Context, a int, b int,
+) (
+	int, error,
+) {
+	results, err := t.Ipc.CallContext(ctx, "TsIpcApi.Div", a, b)
 	if err != nil {
 		return 0, fmt.Errorf("call to TsIpcApi.Div failed: %w", err)
 	}
@@ -36,7 +47,15 @@ func (t *TsIpcApi) XorData(
 ) (
 	[]byte, error,
 ) {
-	results, err := t.Ipc.Call("TsIpcApi.XorData", data1, data2)
+	return t.XorDataContext(context.Background(), data1, data2)
+}
+
+func (t *TsIpcApi) XorDataContext(
+	ctx context.Context, data1 []byte, data2 []byte,
+) (
+	[]byte, error,
+) {
+	results, err := t.Ipc.CallContext(ctx, "TsIpcApi.XorData", data1, data2)
 	if err != nil {
 		return []byte{}, fmt.Errorf("call to TsIpcApi.XorData failed: %w", err)
 	}
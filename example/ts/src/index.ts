@@ -1,13 +1,20 @@
-import {ChildIPC} from 'kitten-ipc';
+import {ChildIPC, IPCError} from 'kitten-ipc';
 import GoIpcApi from './remote.js';
 
+/**
+ * @kittenipc error
+ */
+class ZeroDivisionError extends IPCError {
+    static override readonly code = 'ZeroDivision';
+}
+
 /**
  * @kittenipc api
  */
 class TsIpcApi {
     Div(a: number, b: number): number {
         if (b === 0) {
-            throw new Error('zero division');
+            throw new ZeroDivisionError('zero division');
         }
         return a / b;
     }
@@ -1,6 +1,11 @@
 // Code generated by kitcom. DO NOT EDIT.
 
-import { ParentIPC, ChildIPC } from "kitten-ipc";
+import { ParentIPC, ChildIPC, IPCError, registerError } from "kitten-ipc";
+
+export class ErrZeroDivision extends IPCError {
+  static override readonly code = "ZeroDivision";
+}
+registerError(ErrZeroDivision);
 
 export default class GoIpcApi {
   protected ipc: ParentIPC | ChildIPC;
@@ -26,6 +26,14 @@ type Endpoint struct {
 	Methods []Method
 }
 
+// Error is a sentinel error declared in the API source.
+type Error struct {
+	Name    string
+	Code    string
+	Message string
+}
+
 type Api struct {
 	Endpoints []Endpoint
+	Errors    []Error
 }
@@ -14,15 +14,16 @@ func (p *Parser) AddFile(path string) {
 	p.Files = append(p.Files, path)
 }
 
-func (p *Parser) MapFiles(parseFile func(path string) ([]api.Endpoint, error)) (*api.Api, error) {
+func (p *Parser) MapFiles(parseFile func(path string) (*api.Api, error)) (*api.Api, error) {
 	var apis api.Api
 
 	for _, f := range p.Files {
-		endpoints, err := parseFile(f)
+		fileApi, err := parseFile(f)
 		if err != nil {
 			return nil, fmt.Errorf("parse file: %w", err)
 		}
-		apis.Endpoints = append(apis.Endpoints, endpoints...)
+		apis.Endpoints = append(apis.Endpoints, fileApi.Endpoints...)
+		apis.Errors = append(apis.Errors, fileApi.Errors...)
 	}
 
 	if len(apis.Endpoints) == 0 {
@@ -6,12 +6,14 @@ import (
 	"go/parser"
 	"go/token"
 	"regexp"
+	"strconv"
 
 	"github.com/egor3f/kitten-ipc/kitcom/internal/api"
 	"github.com/egor3f/kitten-ipc/kitcom/internal/common"
 )
 
 var decorComment = regexp.MustCompile(`^//\s?kittenipc:api$`)
+var errorDecorComment = regexp.MustCompile(`^//\s?kittenipc:error$`)
 
 type GoApiParser struct {
 	*common.Parser
@@ -21,8 +23,9 @@ func (p *GoApiParser) Parse() (*api.Api, error) {
 	return p.MapFiles(p.parseFile)
 }
 
-func (p *GoApiParser) parseFile(sourceFile string) ([]api.Endpoint, error) {
+func (p *GoApiParser) parseFile(sourceFile string) (*api.Api, error) {
 	var endpoints []api.Endpoint
+	var apiErrors []api.Error
 
 	fileSet := token.NewFileSet()
 	astFile, err := parser.ParseFile(fileSet, sourceFile, nil, parser.ParseComments|parser.SkipObjectResolution)
@@ -42,6 +45,14 @@ func (p *GoApiParser) parseFile(sourceFile string) ([]api.Endpoint, error) {
 
 		// use only last comment. https://tip.golang.org/doc/comment#syntax
 		lastComment := genDecl.Doc.List[len(genDecl.Doc.List)-1]
+		if errorDecorComment.MatchString(lastComment.Text) {
+			declErrors, err := parseErrorDecl(genDecl)
+			if err != nil {
+				return nil, err
+			}
+			apiErrors = append(apiErrors, declErrors...)
+			continue
+		}
 		if !decorComment.MatchString(lastComment.Text) {
 			continue
 		}
@@ -63,7 +74,7 @@ func (p *GoApiParser) parseFile(sourceFile string) ([]api.Endpoint, error) {
 	}
 
 	if len(endpoints) == 0 {
-		return nil, nil
+		return &api.Api{Errors: apiErrors}, nil
 	}
 
 	for _, decl := range astFile.Decls {
@@ -98,6 +109,10 @@ func (p *GoApiParser) parseFile(sourceFile string) ([]api.Endpoint, error) {
 				var apiMethod api.Method
 				apiMethod.Name = funcDecl.Name.Name
 				for i, param := range funcDecl.Type.Params.List {
+					// context is passed by the library and is not a part of the api
+					if i == 0 && isContextType(param.Type) {
+						continue
+					}
 					apiPar, err := fieldToVal(param, false)
 					if err != nil {
 						return nil, fmt.Errorf("parse parameter %d for method %s: %w", i, apiMethod.Name, err)
@@ -134,7 +149,55 @@ func (p *GoApiParser) parseFile(sourceFile string) ([]api.Endpoint, error) {
 			}
 		}
 	}
-	return endpoints, nil
+	return &api.Api{Endpoints: endpoints, Errors: apiErrors}, nil
+}
+
+// parseErrorDecl parses sentinel declarations of form `var ErrName = kittenipc.NewError("Code", "message")`.
+func parseErrorDecl(genDecl *ast.GenDecl) ([]api.Error, error) {
+	if genDecl.Tok != token.VAR {
+		return nil, fmt.Errorf("kittenipc:error annotation is supported only for variables")
+	}
+
+	var apiErrors []api.Error
+	for _, spec := range genDecl.Specs {
+		valueSpec := spec.(*ast.ValueSpec)
+		if len(valueSpec.Names) != len(valueSpec.Values) {
+			return nil, fmt.Errorf("error sentinels should be initialized")
+		}
+		for i, name := range valueSpec.Names {
+			call, ok := valueSpec.Values[i].(*ast.CallExpr)
+			if !ok || len(call.Args) != 2 {
+				return nil, fmt.Errorf("error %s should be created with kittenipc.NewError(code, message)", name.Name)
+			}
+			var strArgs []string
+			for _, arg := range call.Args {
+				lit, ok := arg.(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					return nil, fmt.Errorf("error %s: code and message should be string literals", name.Name)
+				}
+				str, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					return nil, fmt.Errorf("error %s: unquote %s: %w", name.Name, lit.Value, err)
+				}
+				strArgs = append(strArgs, str)
+			}
+			apiErrors = append(apiErrors, api.Error{
+				Name:    name.Name,
+				Code:    strArgs[0],
+				Message: strArgs[1],
+			})
+		}
+	}
+	return apiErrors, nil
+}
+
+func isContextType(expr ast.Expr) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "context" && sel.Sel.Name == "Context"
 }
 
 func fieldToVal(param *ast.Field, returning bool) (*api.Val, error) {
@@ -40,4 +40,7 @@ func TestGoParser(t *testing.T) {
 	assert.Equal(t, api.TBlob, xor.Params[1].Type)
 	require.Len(t, xor.Ret, 1)
 	assert.Equal(t, api.TBlob, xor.Ret[0].Type)
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, api.Error{Name: "ErrZeroDivision", Code: "ZeroDivision", Message: "zero division"}, result.Errors[0])
 }
@@ -2,6 +2,7 @@ package ts
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os/exec"
@@ -30,6 +31,10 @@ func (g *TypescriptApiGenerator) Generate(apis *api.Api, destFile string) error
 
 	tpl := template.New("tsgen")
 	tpl = tpl.Funcs(map[string]any{
+		"quote": func(s string) (string, error) {
+			quoted, err := json.Marshal(s)
+			return string(quoted), err
+		},
 		"typedef": func(t api.ValType) (string, error) {
 			td, ok := map[api.ValType]string{
 				api.TInt:    "number",
@@ -22,8 +22,9 @@ func (p *TypescriptApiParser) Parse() (*api.Api, error) {
 	return p.MapFiles(p.parseFile)
 }
 
-func (p *TypescriptApiParser) parseFile(sourceFilePath string) ([]api.Endpoint, error) {
+func (p *TypescriptApiParser) parseFile(sourceFilePath string) (*api.Api, error) {
 	var endpoints []api.Endpoint
+	var apiErrors []api.Error
 
 	f, err := os.Open(sourceFilePath)
 	if err != nil {
@@ -51,7 +52,12 @@ func (p *TypescriptApiParser) parseFile(sourceFilePath string) ([]api.Endpoint,
 		}
 		cls := node.AsClassDeclaration()
 
-		if !p.isApiNode(cls) {
+		if p.hasTag(cls, TagCommentError) {
+			apiErrors = append(apiErrors, p.parseErrorClass(cls))
+			return false
+		}
+
+		if !p.hasTag(cls, TagComment) {
 			return false
 		}
 
@@ -105,7 +111,28 @@ func (p *TypescriptApiParser) parseFile(sourceFilePath string) ([]api.Endpoint,
 		return nil, err
 	}
 
-	return endpoints, nil
+	return &api.Api{Endpoints: endpoints, Errors: apiErrors}, nil
+}
+
+// parseErrorClass uses string literal initializer of static `code` property as error code, or class name if there is none.
+func (p *TypescriptApiParser) parseErrorClass(cls *ast.ClassDeclaration) api.Error {
+	apiError := api.Error{
+		Name: cls.Name().Text(),
+		Code: cls.Name().Text(),
+	}
+	for _, member := range cls.MemberList().Nodes {
+		if member.Kind != ast.KindPropertyDeclaration {
+			continue
+		}
+		prop := member.AsPropertyDeclaration()
+		if prop.ModifierFlags()&ast.ModifierFlagsStatic == 0 || prop.Name().Text() != "code" {
+			continue
+		}
+		if prop.Initializer != nil && prop.Initializer.Kind == ast.KindStringLiteral {
+			apiError.Code = prop.Initializer.Text()
+		}
+	}
+	return apiError
 }
 
 func (p *TypescriptApiParser) fieldToVal(typ *ast.TypeNode) (api.ValType, error) {
@@ -132,8 +159,9 @@ func (p *TypescriptApiParser) fieldToVal(typ *ast.TypeNode) (api.ValType, error)
 
 const TagName = "kittenipc"
 const TagComment = "api"
+const TagCommentError = "error"
 
-func (p *TypescriptApiParser) isApiNode(cls *ast.ClassDeclaration) bool {
+func (p *TypescriptApiParser) hasTag(cls *ast.ClassDeclaration, tagComment string) bool {
 	jsDocNodes := cls.JSDoc(nil)
 	if len(jsDocNodes) == 0 {
 		return false
@@ -146,7 +174,7 @@ func (p *TypescriptApiParser) isApiNode(cls *ast.ClassDeclaration) bool {
 		for _, tag := range jsDoc.Tags.Nodes {
 			if tag.TagName().Text() == TagName {
 				for _, com := range tag.Comments() {
-					if strings.TrimSpace(com.Text()) == TagComment {
+					if strings.TrimSpace(com.Text()) == tagComment {
 						return true
 					}
 				}
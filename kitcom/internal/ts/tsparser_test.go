@@ -43,4 +43,7 @@ func TestTsParser(t *testing.T) {
 	assert.Equal(t, api.TBlob, xor.Params[1].Type)
 	require.Len(t, xor.Ret, 1)
 	assert.Equal(t, api.TBlob, xor.Ret[0].Type)
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, api.Error{Name: "ZeroDivisionError", Code: "ZeroDivision", Message: ""}, result.Errors[0])
 }
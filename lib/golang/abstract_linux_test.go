@@ -0,0 +1,41 @@
+package golang
+
+import (
+	"net"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbstractSocket(t *testing.T) {
+	metrics := NewMemoryMetrics()
+	p, err := NewParent(exec.Command("sh", "-c", "exec sleep 15"), &Options{AbstractSocket: true, Metrics: metrics})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(p.socketPath, "@"))
+	assert.Empty(t, p.socketDir)
+	assert.Equal(t, PeerCheckPid, p.peerCheck)
+
+	started := make(chan error, 1)
+	go func() {
+		started <- p.Start()
+	}()
+
+	var conn net.Conn
+	require.Eventually(t, func() bool {
+		conn, err = net.Dial("unix", p.socketPath)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	defer conn.Close()
+	require.NoError(t, sendHandshake(conn, p.token))
+	require.Eventually(t, func() bool {
+		return metrics.Counter(MetricHandshakeFailures, Labels{}) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_ = p.cmd.Process.Kill()
+	var credErr *PeerCredError
+	assert.ErrorAs(t, <-started, &credErr)
+}
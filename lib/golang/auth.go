@@ -0,0 +1,73 @@
+package golang
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+const maxHandshakeLength = 4096
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// sendHandshake is written directly to conn, so the token is neither logged nor recorded.
+func sendHandshake(conn net.Conn, token string) error {
+	data, err := json.Marshal(Message{Type: MsgHandshake, Token: token})
+	if err != nil {
+		return fmt.Errorf("marshal handshake: %w", err)
+	}
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write handshake: %w", err)
+	}
+	return nil
+}
+
+// authenticate reads the handshake of a connected child and checks its token.
+// The handshake is read byte by byte, so nothing after it is consumed from conn.
+func authenticate(conn net.Conn, token string, timeout time.Duration) error {
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return fmt.Errorf("set handshake deadline: %w", err)
+	}
+
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return fmt.Errorf("read handshake: %w", err)
+		}
+		if b[0] == '\n' {
+			break
+		}
+		if len(line) >= maxHandshakeLength {
+			return fmt.Errorf("handshake is longer than %d bytes", maxHandshakeLength)
+		}
+		line = append(line, b[0])
+	}
+
+	var msg Message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return fmt.Errorf("unmarshal handshake: %w", err)
+	}
+	if msg.Type != MsgHandshake {
+		return fmt.Errorf("expected handshake, got message type %d", msg.Type)
+	}
+	if subtle.ConstantTimeCompare([]byte(msg.Token), []byte(token)) != 1 {
+		return fmt.Errorf("invalid token")
+	}
+
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return fmt.Errorf("reset handshake deadline: %w", err)
+	}
+	return nil
+}
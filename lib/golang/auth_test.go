@@ -0,0 +1,155 @@
+package golang
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticate(t *testing.T) {
+	authenticatePipe := func(write func(conn net.Conn)) (net.Conn, error) {
+		server, client := net.Pipe()
+		t.Cleanup(func() {
+			_ = server.Close()
+			_ = client.Close()
+		})
+		go write(client)
+		return server, authenticate(server, "secret", time.Second)
+	}
+
+	t.Run("valid token, following data is kept", func(t *testing.T) {
+		conn, err := authenticatePipe(func(conn net.Conn) {
+			_ = sendHandshake(conn, "secret")
+			_, _ = conn.Write([]byte("next\n"))
+		})
+		require.NoError(t, err)
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "next\n", line)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := authenticatePipe(func(conn net.Conn) {
+			_ = sendHandshake(conn, "guess")
+		})
+		assert.ErrorContains(t, err, "invalid token")
+	})
+
+	t.Run("not a handshake", func(t *testing.T) {
+		_, err := authenticatePipe(func(conn net.Conn) {
+			_, _ = conn.Write([]byte(`{"type":1,"method":"A.B","token":"secret"}` + "\n"))
+		})
+		assert.ErrorContains(t, err, "expected handshake")
+	})
+
+	t.Run("too long", func(t *testing.T) {
+		_, err := authenticatePipe(func(conn net.Conn) {
+			_, _ = conn.Write(make([]byte, maxHandshakeLength+1))
+		})
+		assert.ErrorContains(t, err, "longer than")
+	})
+
+	t.Run("silent peer times out", func(t *testing.T) {
+		server, client := net.Pipe()
+		defer client.Close()
+		err := authenticate(server, "secret", 50*time.Millisecond)
+		assert.ErrorContains(t, err, "read handshake")
+	})
+}
+
+func TestParentLimitsPendingHandshakes(t *testing.T) {
+	metrics := NewMemoryMetrics()
+	p, err := NewParent(exec.Command("../testdata/sleep15.sh"), &Options{Metrics: metrics})
+	require.NoError(t, err)
+
+	started := make(chan error, 1)
+	go func() {
+		started <- p.Start()
+	}()
+	require.Eventually(t, func() bool {
+		_, err = os.Stat(p.socketPath)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	silent := make([]net.Conn, maxPendingHandshakes)
+	for i := range silent {
+		silent[i], err = net.Dial("unix", p.socketPath)
+		require.NoError(t, err)
+		defer silent[i].Close()
+	}
+	// connections are accepted in order, so the silent ones hold all slots when the next one is accepted
+	extra, err := net.Dial("unix", p.socketPath)
+	require.NoError(t, err)
+	defer extra.Close()
+	_ = extra.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = extra.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF, "extra connection should be closed right away")
+	assert.Equal(t, float64(1), metrics.Counter(MetricHandshakeFailures, Labels{}))
+
+	// a finished handshake frees its slot for the child
+	_ = silent[0].Close()
+	require.Eventually(t, func() bool {
+		return metrics.Counter(MetricHandshakeFailures, Labels{}) == 2
+	}, time.Second, 10*time.Millisecond)
+	child, err := net.Dial("unix", p.socketPath)
+	require.NoError(t, err)
+	defer child.Close()
+	require.NoError(t, sendHandshake(child, p.token))
+	require.NoError(t, <-started)
+
+	_ = p.cmd.Process.Kill()
+	_ = p.Wait()
+}
+
+func TestParentRejectsUnauthenticated(t *testing.T) {
+	metrics := NewMemoryMetrics()
+	p, err := NewParent(exec.Command("../testdata/sleep15.sh"), &Options{Metrics: metrics})
+	require.NoError(t, err)
+
+	started := make(chan error, 1)
+	go func() {
+		started <- p.Start()
+	}()
+
+	var info os.FileInfo
+	require.Eventually(t, func() bool {
+		info, err = os.Stat(p.socketPath)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	dirInfo, err := os.Stat(p.socketDir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), dirInfo.Mode().Perm())
+
+	impostor, err := net.Dial("unix", p.socketPath)
+	require.NoError(t, err)
+	require.NoError(t, sendHandshake(impostor, "guess"))
+	_, err = impostor.Read(make([]byte, 1))
+	assert.Error(t, err, "impostor connection should be closed")
+
+	silent, err := net.Dial("unix", p.socketPath)
+	require.NoError(t, err)
+	defer silent.Close()
+
+	child, err := net.Dial("unix", p.socketPath)
+	require.NoError(t, err)
+	defer child.Close()
+	require.NoError(t, sendHandshake(child, p.token))
+
+	require.NoError(t, <-started)
+	assert.Equal(t, float64(1), metrics.Counter(MetricHandshakeFailures, Labels{}))
+	assert.Equal(t, float64(1), metrics.Counter(MetricHandshakes, Labels{}))
+	assert.Equal(t, float64(1), metrics.Counter(MetricChildStarts, Labels{}))
+
+	_ = p.cmd.Process.Kill()
+	_ = p.Wait()
+	_, err = os.Stat(p.socketDir)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,70 @@
+package golang
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// benchWorkers is the number of goroutines making concurrent calls.
+const benchWorkers = 64
+
+// BenchmarkSmallCalls makes small concurrent calls over a unix socket at a target rate, zero meaning as fast as possible.
+// Compare calls/s and µs/call, the latency from the scheduled start of a call to its response,
+// with and without write batching. Allocations count both sides of the call. On a single CPU, rate=0 gives
+// about 95k calls/s with batching and 65-85k without, at 30 allocs/op either way.
+func BenchmarkSmallCalls(b *testing.B) {
+	for _, batching := range []bool{true, false} {
+		for _, rate := range []int{1_000, 10_000, 100_000, 0} {
+			name := fmt.Sprintf("batching=%v/rate=%d", batching, rate)
+			b.Run(name, func(b *testing.B) {
+				benchmarkCalls(b, &Options{DisableWriteBatching: !batching, SocketHandoff: SocketHandoffEnv}, rate)
+			})
+		}
+	}
+}
+
+func benchmarkCalls(b *testing.B, opts *Options, rate int) {
+	launcher := &inProcessLauncher{apis: []any{&echoEndpoint{}}, opts: opts}
+	p, err := NewParentWithLauncher(context.Background(), launcher, opts)
+	require.NoError(b, err)
+	require.NoError(b, p.Start())
+	b.Cleanup(func() { _ = p.Stop() })
+
+	var next, latency atomic.Int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range benchWorkers {
+		wg.Go(func() {
+			for {
+				i := next.Add(1) - 1
+				if i >= int64(b.N) {
+					return
+				}
+				scheduled := time.Now()
+				if rate > 0 {
+					scheduled = start.Add(time.Duration(i) * time.Second / time.Duration(rate))
+					time.Sleep(time.Until(scheduled))
+				}
+				if _, err := p.Call("echoEndpoint.Echo", "ping"); err != nil {
+					b.Error(err)
+					return
+				}
+				latency.Add(int64(time.Since(scheduled)))
+			}
+		})
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	b.StopTimer()
+
+	b.ReportMetric(float64(b.N)/elapsed.Seconds(), "calls/s")
+	b.ReportMetric(float64(latency.Load())/float64(b.N)/1e3, "µs/call")
+}
@@ -9,28 +9,25 @@ import (
 
 type ChildIPC struct {
 	*ipcCommon
+	token string
 }
 
 func NewChild(opts *Options, localApis ...any) (*ChildIPC, error) {
-	if opts == nil {
-		opts = &Options{}
-	}
 	c := ChildIPC{
-		ipcCommon: &ipcCommon{
-			localApis:     mapTypeNames(localApis),
-			pendingCalls:  make(map[int64]*pendingCall),
-			errCh:         make(chan error, 1),
-			ctx:           context.Background(),
-			debugMessages: opts.DebugMessages,
-		},
+		ipcCommon: newIpcCommon(context.Background(), opts, localApis),
 	}
 
-	socketPath := socketPathFromArgs()
+	socketPath := socketPathFromEnvOrArgs()
 	if socketPath == "" {
 		return nil, fmt.Errorf("ipc socket path is missing")
 	}
 	c.socketPath = socketPath
 
+	// The socket and token are not inherited by processes the child starts
+	c.token = os.Getenv(ipcTokenEnv)
+	_ = os.Unsetenv(ipcTokenEnv)
+	_ = os.Unsetenv(ipcSocketEnv)
+
 	return &c, nil
 }
 
@@ -39,22 +36,30 @@ func (c *ChildIPC) Start() error {
 	if err != nil {
 		return fmt.Errorf("connect to parent socket: %w", err)
 	}
+	if err := sendHandshake(conn, c.token); err != nil {
+		_ = conn.Close()
+		return err
+	}
 	c.conn = conn
+	c.logger.Info("connected to parent", "socket", c.socketPath)
+	c.connected()
 	go c.readConn()
 	return nil
 }
 
 func (c *ChildIPC) Wait() error {
-	err := <-c.errCh
-	if err != nil {
-		return fmt.Errorf("ipc error: %w", err)
+	if err := <-c.errCh; err != nil {
+		return c.ipcError()
 	}
 	return nil
 }
 
-// socketPathFromArgs parses --ipc-socket from os.Args without calling flag.Parse(),
-// which would interfere with the host application's flag handling.
-func socketPathFromArgs() string {
+// socketPathFromEnvOrArgs takes the socket path from KITTEN_IPC_SOCKET or else parses --ipc-socket
+// from os.Args without calling flag.Parse(), which would interfere with the host application's flag handling.
+func socketPathFromEnvOrArgs() string {
+	if socketPath := os.Getenv(ipcSocketEnv); socketPath != "" {
+		return socketPath
+	}
 	for i, arg := range os.Args {
 		if arg == ipcSocketArg && i+1 < len(os.Args) {
 			return os.Args[i+1]
@@ -0,0 +1,31 @@
+package golang
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSocketPathFromEnvOrArgs(t *testing.T) {
+	args := os.Args
+	t.Cleanup(func() { os.Args = args })
+
+	t.Run("args", func(t *testing.T) {
+		t.Setenv(ipcSocketEnv, "")
+		os.Args = []string{"child", "--verbose", ipcSocketArg, "/tmp/a.sock"}
+		assert.Equal(t, "/tmp/a.sock", socketPathFromEnvOrArgs())
+	})
+
+	t.Run("env takes precedence", func(t *testing.T) {
+		t.Setenv(ipcSocketEnv, "/tmp/b.sock")
+		os.Args = []string{"child", ipcSocketArg, "/tmp/a.sock"}
+		assert.Equal(t, "/tmp/b.sock", socketPathFromEnvOrArgs())
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		t.Setenv(ipcSocketEnv, "")
+		os.Args = []string{"child", ipcSocketArg}
+		assert.Empty(t, socketPathFromEnvOrArgs())
+	})
+}
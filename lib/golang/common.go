@@ -1,34 +1,135 @@
 package golang
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net"
 	"reflect"
+	"runtime/debug"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 type IpcCommon interface {
 	Call(method string, params ...any) (Vals, error)
+	CallContext(ctx context.Context, method string, params ...any) (Vals, error)
 	ConvType(needType, gotType reflect.Type, arg any) any
 }
 
 type callResult struct {
 	vals Vals
 	err  error
+	size int
 }
 
 type pendingCall struct {
 	resultChan chan callResult
 }
 
+// SocketHandoff is the way the child receives the socket address. Children look for both.
+type SocketHandoff int
+
+const (
+	// SocketHandoffArgs appends --ipc-socket <address> to the command arguments.
+	SocketHandoffArgs SocketHandoff = iota
+	// SocketHandoffEnv sets the KITTEN_IPC_SOCKET environment variable, e.g. for children with strict argument parsers
+	// or commands wrapped in npx or sh -c.
+	SocketHandoffEnv
+)
+
 type Options struct {
+	// DebugMessages logs every message to stderr when Logger is not set.
 	DebugMessages bool
+
+	// Logger receives connection, call and error events. Message payloads are logged at LevelTrace.
+	Logger *slog.Logger
+	// LogPayloadLimit truncates logged payloads, 1024 bytes by default. Negative value disables truncation.
+	LogPayloadLimit int
+	// LogRedact modifies messages before they are logged, e.g. to hide secrets. Blobs are replaced anyway.
+	LogRedact func(msg Message) Message
+
+	// ClientInterceptors wrap every outgoing call, the first one being the outermost.
+	ClientInterceptors []ClientInterceptor
+	// ServerInterceptors wrap every incoming call, the first one being the outermost.
+	ServerInterceptors []ServerInterceptor
+
+	// PropagateStacks sends the stack of a panicked incoming call to the caller. Intended for debugging.
+	PropagateStacks bool
+
+	// Tracer starts client and server spans around calls. Span context is sent as W3C traceparent metadata.
+	Tracer Tracer
+
+	// Metrics receives call counters, latencies and traffic sizes, see MemoryMetrics.
+	Metrics MetricsSink
+
+	// OnChildOutput receives lines the child writes to stdout and stderr. Writers set on the command still receive output,
+	// otherwise it goes to os.Stdout and os.Stderr only if neither OnChildOutput nor LogChildOutput is set.
+	OnChildOutput func(line OutputLine)
+	// LogChildOutput logs lines of child output with Logger, unless OnChildOutput is set. It requires Logger.
+	LogChildOutput bool
+	// StderrTail is the number of last stderr bytes of the child attached to ChildExitError, none by default.
+	// Keeping the tail pipes stderr of the child through the parent, so the child no longer sees a terminal there.
+	StderrTail int
+
+	// SharedProcessGroup keeps the child in the process group of the parent. By default the child gets its own group,
+	// so signals reach the whole tree started by wrappers like sh -c or npx. Unix only.
+	SharedProcessGroup bool
+	// KillGracePeriod is the time the child has to exit after SIGINT before it is killed, 5 seconds by default.
+	// Negative value disables killing.
+	KillGracePeriod time.Duration
+	// ParentDeathSignal is delivered to the child when the parent dies, see PR_SET_PDEATHSIG. Linux only.
+	// Note that it fires when the OS thread which started the child exits.
+	ParentDeathSignal syscall.Signal
+
+	// Limits restrict resources of the child. Linux only.
+	Limits *ResourceLimits
+
+	// SocketHandoff selects how ParentIPC passes the socket address to the child.
+	SocketHandoff SocketHandoff
+	// AbstractSocket makes ParentIPC listen on an abstract unix socket, which leaves no file behind.
+	// Abstract sockets ignore filesystem permissions, so PeerCheck is at least PeerCheckPid. Linux only.
+	AbstractSocket bool
+	// PeerCheck makes ParentIPC verify the connected peer is the spawned child using SO_PEERCRED. Linux only.
+	PeerCheck PeerCheck
+
+	// MaxMessageSize limits incoming messages, 1 GB by default. Larger calls are answered with ErrMessageTooLarge
+	// and larger responses fail their calls with it, without breaking the connection.
+	MaxMessageSize int
+	// FrameSize is the largest frame written to the connection, 1 MB by default. Larger messages are sent in chunks,
+	// taking turns with other messages. It should not exceed MaxMessageSize of the other side. Negative value disables chunking.
+	FrameSize int
+	// DisableWriteBatching writes every message right away. By default messages queued by concurrent calls
+	// are written together, saving syscalls at high call rates.
+	DisableWriteBatching bool
+	// MethodLanes sets the lane of calls to methods, by full name like "Api.Method", and of responses to them.
+	// Other messages go in LaneNormal. Each side orders only messages it writes.
+	MethodLanes map[string]Lane
+	// AcceptTimeout is how long ParentIPC waits for the child to connect and authenticate, 10 seconds by default.
+	AcceptTimeout time.Duration
+
+	// Recorder receives every message sent and received as RecordEntry JSON lines, see Replay.
+	Recorder io.Writer
+
+	// OnConnected is called when the connection to the other side is established.
+	// Hooks are called from ipc goroutines and should return quickly.
+	OnConnected func()
+	// OnDisconnected is called when the connection is closed, with the error which broke it, if any.
+	OnDisconnected func(err error)
+	// OnError is called with every internal ipc error, including warnings Wait does not return.
+	OnError func(err *InternalError)
+	// ErrorPolicy decides which internal errors make Wait return, see ErrorSeverity.
+	ErrorPolicy ErrorPolicy
+
+	// OnChildExit is called by ParentIPC when the child exits, whether it failed or not.
+	OnChildExit func(exit *ChildExitError)
 }
 
 type ipcCommon struct {
@@ -36,33 +137,157 @@ type ipcCommon struct {
 	socketPath              string
 	conn                    net.Conn
 	errCh                   chan error
+	errs                    []error
+	errMu                   sync.Mutex
 	nextId                  int64
 	pendingCalls            map[int64]*pendingCall
 	processingIncomingCalls atomic.Int64
 	stopRequested           atomic.Bool
 	mu                      sync.Mutex
-	writeMu                 sync.Mutex
+	frames                  *frameWriter
+	frameSize               int
+	writeBatching           bool
+	methodLanes             map[string]Lane
+	writerMu                sync.Mutex
 	ctx                     context.Context
-	debugMessages           bool
+	logger                  *slog.Logger
+	logPayloadLimit         int
+	logRedact               func(msg Message) Message
+	propagateStacks         bool
+	tracer                  Tracer
+	metrics                 MetricsSink
+	recorder                io.Writer
+	recordMu                sync.Mutex
+	invoker                 Invoker
+	handler                 Handler
+	onConnected             func()
+	onDisconnected          func(err error)
+	onError                 func(err *InternalError)
+	errorPolicy             ErrorPolicy
+	maxMessageSize          int
+}
+
+func newIpcCommon(ctx context.Context, opts *Options, localApis []any) *ipcCommon {
+	if opts == nil {
+		opts = &Options{}
+	}
+	ipc := &ipcCommon{
+		localApis:       mapTypeNames(localApis),
+		pendingCalls:    make(map[int64]*pendingCall),
+		errCh:           make(chan error, 1),
+		ctx:             ctx,
+		logger:          newLogger(opts),
+		logPayloadLimit: opts.LogPayloadLimit,
+		logRedact:       opts.LogRedact,
+		propagateStacks: opts.PropagateStacks,
+		tracer:          opts.Tracer,
+		metrics:         opts.Metrics,
+		recorder:        opts.Recorder,
+		onConnected:     opts.OnConnected,
+		onDisconnected:  opts.OnDisconnected,
+		onError:         opts.OnError,
+		errorPolicy:     opts.ErrorPolicy,
+		maxMessageSize:  opts.MaxMessageSize,
+		frameSize:       opts.FrameSize,
+		methodLanes:     opts.MethodLanes,
+		writeBatching:   !opts.DisableWriteBatching,
+	}
+	switch {
+	case ipc.frameSize == 0:
+		ipc.frameSize = defaultFrameSize
+	case ipc.frameSize < 0:
+		ipc.frameSize = 0
+	default:
+		ipc.frameSize = max(ipc.frameSize, minFrameSize)
+	}
+	if ipc.maxMessageSize == 0 {
+		ipc.maxMessageSize = defaultMaxMessageSize
+	}
+	if ipc.logPayloadLimit == 0 {
+		ipc.logPayloadLimit = defaultLogPayloadLimit
+	}
+	ipc.invoker = chainClientInterceptors(opts.ClientInterceptors, ipc.invoke)
+	ipc.handler = chainServerInterceptors(opts.ServerInterceptors, ipc.invokeLocal)
+	return ipc
 }
 
 func (ipc *ipcCommon) readConn() {
-	scn := bufio.NewScanner(ipc.conn)
-	scn.Buffer(nil, maxMessageLength)
-	for scn.Scan() {
-		var msg Message
-		msgBytes := scn.Bytes()
-		if ipc.debugMessages {
-			log.Printf("[ipc recv] %s", string(msgBytes))
+	var readErr error
+	defer func() {
+		if ipc.onDisconnected != nil {
+			ipc.onDisconnected(readErr)
+		}
+	}()
+
+	rd := newMessageReader(ipc.conn, ipc.maxMessageSize)
+	chunks := newChunkAssembler(ipc.maxMessageSize)
+	for {
+		msgBytes, oversized, err := rd.next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !ipc.stopRequested.Load() {
+				readErr = err
+				ipc.raiseErr(SeverityFatal, err)
+			}
+			return
+		}
+		if oversized != nil {
+			if msgType, id, ok := messageHeader(oversized.prefix); ok && msgType == MsgChunk {
+				// The rest of the chunked message is skipped too, it is rejected after the last chunk
+				oversized, complete, err := chunks.skip(id, oversized)
+				if err != nil {
+					readErr = fmt.Errorf("read chunk: %w", err)
+					ipc.raiseErr(SeverityFatal, readErr)
+					return
+				}
+				if complete {
+					ipc.rejectOversized(oversized)
+				}
+				continue
+			}
+			ipc.rejectOversized(oversized)
+			continue
+		}
+
+		var f frame
+		if err := json.Unmarshal(msgBytes, &f); err != nil {
+			readErr = fmt.Errorf("unmarshal message: %w", err)
+			ipc.raiseErr(SeverityFatal, readErr)
+			return
 		}
-		if err := json.Unmarshal(msgBytes, &msg); err != nil {
-			ipc.raiseErr(fmt.Errorf("unmarshal message: %w", err))
-			break
+		if f.Type == MsgChunk {
+			data, oversized, complete, err := chunks.add(&f)
+			if err != nil {
+				readErr = fmt.Errorf("read chunk: %w", err)
+				ipc.raiseErr(SeverityFatal, readErr)
+				return
+			}
+			if !complete {
+				continue
+			}
+			if oversized != nil {
+				ipc.rejectOversized(oversized)
+				continue
+			}
+			f = frame{}
+			if err := json.Unmarshal(data, &f.Message); err != nil {
+				readErr = fmt.Errorf("unmarshal chunked message: %w", err)
+				ipc.raiseErr(SeverityFatal, readErr)
+				return
+			}
+			msgBytes = data
 		}
+		msg := f.Message
+		msg.size = len(msgBytes)
+		ipc.logPayload("message received", msg)
+		ipc.record(DirectionReceived, msg)
 		ipc.handleIncomingMsg(msg)
 	}
-	if err := scn.Err(); err != nil {
-		ipc.raiseErr(err)
+}
+
+// connected is called once the connection is established, before reading from it.
+func (ipc *ipcCommon) connected() {
+	if ipc.onConnected != nil {
+		ipc.onConnected()
 	}
 }
 
@@ -75,24 +300,35 @@ func (ipc *ipcCommon) handleIncomingMsg(msg Message) {
 	}
 }
 
-func (ipc *ipcCommon) sendMsg(msg Message) error {
-	data, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("marshal message: %w", err)
-	}
-	if ipc.debugMessages {
-		log.Printf("[ipc send] %s", string(data))
+var errMarshalMessage = errors.New("marshal message")
+
+// sendMsg returns the size of the written message. The message is not sent if ctx is done while it waits in the queue.
+func (ipc *ipcCommon) sendMsg(ctx context.Context, msg Message, lane Lane) (int, error) {
+	buf := getBuffer()
+	if err := buf.enc.Encode(msg); err != nil {
+		// The buffer is dropped, so a failed encoder is not reused
+		return 0, fmt.Errorf("%w: %w", errMarshalMessage, err)
 	}
-	data = append(data, '\n')
+	defer putBuffer(buf)
+	// The writer is done with data once write returns, so the buffer can be reused
+	data := bytes.TrimSuffix(buf.Bytes(), []byte{'\n'})
+	ipc.logPayload("message sent", msg)
+	ipc.record(DirectionSent, msg)
 
-	ipc.writeMu.Lock()
-	_, writeErr := ipc.conn.Write(data)
-	ipc.writeMu.Unlock()
-	if writeErr != nil {
-		return fmt.Errorf("write message: %w", writeErr)
+	if err := ipc.writer().write(ctx, data, lane); err != nil {
+		return 0, err
 	}
+	return len(data), nil
+}
 
-	return nil
+// writer returns the frame writer of the connection, starting it on first use.
+func (ipc *ipcCommon) writer() *frameWriter {
+	ipc.writerMu.Lock()
+	defer ipc.writerMu.Unlock()
+	if ipc.frames == nil {
+		ipc.frames = newFrameWriter(ipc.conn, ipc.frameSize, ipc.writeBatching, ipc.observeQueueDelay, ipc.queueLengthChanged)
+	}
+	return ipc.frames
 }
 
 func (ipc *ipcCommon) handleIncomingCall(msg Message) {
@@ -103,35 +339,60 @@ func (ipc *ipcCommon) handleIncomingCall(msg Message) {
 	ipc.processingIncomingCalls.Add(1)
 	defer ipc.processingIncomingCalls.Add(-1)
 
+	ctx := ipc.ctx
+	if len(msg.Metadata) > 0 {
+		ctx = WithMetadata(ctx, msg.Metadata)
+	}
+	ctx, obs := ipc.startCall(ctx, msg.Method, true)
+	obs.bytesReceived = msg.size
+	finish := func(results Vals, err error) {
+		obs.bytesSent = ipc.sendResponse(msg.Id, ipc.methodLane(msg.Method), results, err)
+		obs.end(ctx, err)
+	}
+
 	defer func() {
-		if err := recover(); err != nil {
-			ipc.sendResponse(msg.Id, nil, fmt.Errorf("handle call panicked: %s", err))
+		if r := recover(); r != nil {
+			var err error = ErrInternal.Errorf("handle call panicked: %s", r)
+			if ipc.propagateStacks {
+				err = &stackError{error: err, stack: string(debug.Stack())}
+			}
+			finish(nil, err)
 		}
 	}()
 
-	method, err := ipc.findMethod(msg.Method)
+	results, err := ipc.handler(ctx, msg.Method, msg.Args)
+	finish(results, err)
+}
+
+// invokeLocal calls a method of a local API with arguments converted to its parameter types.
+// Methods accepting context.Context as the first parameter receive ctx.
+func (ipc *ipcCommon) invokeLocal(ctx context.Context, methodName string, args Vals) (Vals, error) {
+	method, err := ipc.findMethod(methodName)
 	if err != nil {
-		ipc.sendResponse(msg.Id, nil, fmt.Errorf("find method: %w", err))
-		return
+		return nil, fmt.Errorf("find method: %w", err)
 	}
 
-	argsCount := method.Type().NumIn()
-	if len(msg.Args) != argsCount {
-		ipc.sendResponse(msg.Id, nil, fmt.Errorf("args count mismatch: expected %d, got %d", argsCount, len(msg.Args)))
-		return
+	var argVals []reflect.Value
+	var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	if method.Type().NumIn() > 0 && method.Type().In(0) == contextType {
+		argVals = append(argVals, reflect.ValueOf(ctx))
+	}
+
+	argsCount := method.Type().NumIn() - len(argVals)
+	if len(args) != argsCount {
+		return nil, ErrInvalidArgument.Errorf("args count mismatch: expected %d, got %d", argsCount, len(args))
 	}
 
-	var args []reflect.Value
-	for i, arg := range msg.Args {
-		paramType := method.Type().In(i)
+	for _, arg := range args {
+		paramType := method.Type().In(len(argVals))
 		argType := reflect.TypeOf(arg)
 		arg = ipc.ConvType(paramType, argType, arg)
-		args = append(args, reflect.ValueOf(arg))
+		argVals = append(argVals, reflect.ValueOf(arg))
 	}
 
 	var errorType = reflect.TypeOf((*error)(nil)).Elem()
 
-	allResultVals := method.Call(args)
+	allResultVals := method.Call(argVals)
 	var retResultVals []reflect.Value
 	var errResultVal reflect.Value
 	if len(allResultVals) > 0 {
@@ -143,7 +404,7 @@ func (ipc *ipcCommon) handleIncomingCall(msg Message) {
 		}
 	}
 
-	var results []any
+	var results Vals
 	for _, resVal := range retResultVals {
 		results = append(results, resVal.Interface())
 	}
@@ -153,31 +414,32 @@ func (ipc *ipcCommon) handleIncomingCall(msg Message) {
 		resultError = errResultVal.Interface().(error)
 	}
 
-	ipc.sendResponse(msg.Id, results, resultError)
+	return results, resultError
 }
 
 func (ipc *ipcCommon) findMethod(methodName string) (reflect.Value, error) {
 	parts := strings.Split(methodName, ".")
 	if len(parts) != 2 {
-		return reflect.Value{}, fmt.Errorf("invalid method: %s", methodName)
+		return reflect.Value{}, ErrMethodNotFound.Errorf("invalid method: %s", methodName)
 	}
 
 	endpointName, methodName := parts[0], parts[1]
 
 	localApi, ok := ipc.localApis[endpointName]
 	if !ok {
-		return reflect.Value{}, fmt.Errorf("endpoint not found: %s", endpointName)
+		return reflect.Value{}, ErrMethodNotFound.Errorf("endpoint not found: %s", endpointName)
 	}
 
 	method := reflect.ValueOf(localApi).MethodByName(methodName)
 	if !method.IsValid() {
-		return reflect.Value{}, fmt.Errorf("method not found: %s", methodName)
+		return reflect.Value{}, ErrMethodNotFound.Errorf("method not found: %s", methodName)
 	}
 
 	return method, nil
 }
 
-func (ipc *ipcCommon) sendResponse(id int64, result []any, err error) {
+// sendResponse returns the size of the sent response.
+func (ipc *ipcCommon) sendResponse(id int64, lane Lane, result Vals, err error) int {
 	msg := Message{
 		Type:   MsgResponse,
 		Id:     id,
@@ -185,38 +447,61 @@ func (ipc *ipcCommon) sendResponse(id int64, result []any, err error) {
 	}
 
 	if err != nil {
-		msg.Error = err.Error()
+		msg.Error = newErrorPayload(err)
 	}
 
-	if err := ipc.sendMsg(msg); err != nil {
-		ipc.raiseErr(fmt.Errorf("send response for id=%d: %w", id, err))
+	size, err := ipc.sendMsg(ipc.ctx, msg, lane)
+	if errors.Is(err, errMarshalMessage) {
+		// The connection is fine, so the caller still gets a response
+		ipc.raiseErr(SeverityWarning, fmt.Errorf("send response for id=%d: %w", id, err))
+		msg.Result = nil
+		msg.Error = newErrorPayload(ErrInternal.Errorf("marshal response: %w", err))
+		size, err = ipc.sendMsg(ipc.ctx, msg, lane)
+	}
+	if err != nil {
+		ipc.raiseErr(SeverityFatal, fmt.Errorf("send response for id=%d: %w", id, err))
 	}
+	return size
 }
 
 func (ipc *ipcCommon) handleOutgoingResponse(msg Message) {
-	ipc.mu.Lock()
-	call, ok := ipc.pendingCalls[msg.Id]
-	if ok {
-		delete(ipc.pendingCalls, msg.Id)
+	res := callResult{size: msg.size}
+	if msg.Error == nil {
+		res.vals = msg.Result
+	} else {
+		res.err = msg.Error.remoteError()
 	}
-	ipc.mu.Unlock()
+	ipc.completeCall(msg.Id, res)
+}
 
+// completeCall passes the result to the pending call with the id.
+func (ipc *ipcCommon) completeCall(id int64, res callResult) {
+	call, ok := ipc.removePendingCall(id)
 	if !ok {
-		ipc.raiseErr(fmt.Errorf("received response for unknown call id: %d", msg.Id))
+		// Usually a late response to a call which has timed out or was cancelled
+		ipc.raiseErr(SeverityWarning, fmt.Errorf("received response for unknown call id: %d", id))
 		return
 	}
-
-	var res callResult
-	if msg.Error == "" {
-		res = callResult{vals: msg.Result}
-	} else {
-		res = callResult{err: fmt.Errorf("remote error: %s", msg.Error)}
-	}
 	call.resultChan <- res
 	close(call.resultChan)
 }
 
 func (ipc *ipcCommon) Call(method string, params ...any) (Vals, error) {
+	return ipc.invoker(ipc.ctx, method, params)
+}
+
+// CallContext calls a remote method sending metadata of ctx. The call is abandoned when ctx is done.
+func (ipc *ipcCommon) CallContext(ctx context.Context, method string, params ...any) (Vals, error) {
+	return ipc.invoker(ctx, method, params)
+}
+
+// invoke sends a call to the remote process and waits for its response.
+func (ipc *ipcCommon) invoke(ctx context.Context, method string, params Vals) (vals Vals, err error) {
+	ctx, obs := ipc.startCall(ctx, method, false)
+	defer func() {
+		obs.end(ctx, err)
+	}()
+
 	if ipc.conn == nil {
 		return nil, fmt.Errorf("ipc is not connected to remote process socket")
 	}
@@ -233,6 +518,7 @@ func (ipc *ipcCommon) Call(method string, params ...any) (Vals, error) {
 	}
 	ipc.pendingCalls[id] = call
 	ipc.mu.Unlock()
+	ipc.pendingCallsChanged(1)
 
 	if params == nil {
 		params = make([]any, 0)
@@ -243,43 +529,61 @@ func (ipc *ipcCommon) Call(method string, params ...any) (Vals, error) {
 	}
 
 	msg := Message{
-		Type:   MsgCall,
-		Id:     id,
-		Method: method,
-		Args:   params,
+		Type:     MsgCall,
+		Id:       id,
+		Method:   method,
+		Args:     params,
+		Metadata: MetadataFromContext(ctx),
 	}
 
-	if err := ipc.sendMsg(msg); err != nil {
-		ipc.mu.Lock()
-		delete(ipc.pendingCalls, id)
-		ipc.mu.Unlock()
+	obs.bytesSent, err = ipc.sendMsg(ctx, msg, ipc.methodLane(method))
+	if err != nil {
+		ipc.removePendingCall(id)
+		if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			return nil, ctxErr
+		}
 		return nil, fmt.Errorf("send call: %w", err)
 	}
 
 	select {
 	case result := <-call.resultChan:
+		obs.bytesReceived = result.size
 		return result.vals, result.err
+	case <-ctx.Done():
+		ipc.removePendingCall(id)
+		return nil, ctx.Err()
 	case <-ipc.ctx.Done():
-		ipc.mu.Lock()
-		delete(ipc.pendingCalls, id)
-		ipc.mu.Unlock()
+		ipc.removePendingCall(id)
 		return nil, ipc.ctx.Err()
 	}
 }
 
-func (ipc *ipcCommon) raiseErr(err error) {
-	select {
-	case ipc.errCh <- err:
-	default:
+// removePendingCall deletes the pending call with the id, reporting whether it was there.
+func (ipc *ipcCommon) removePendingCall(id int64) (*pendingCall, bool) {
+	ipc.mu.Lock()
+	call, ok := ipc.pendingCalls[id]
+	if ok {
+		delete(ipc.pendingCalls, id)
 	}
+	ipc.mu.Unlock()
+	if ok {
+		ipc.pendingCallsChanged(-1)
+	}
+	return call, ok
 }
 
 func (ipc *ipcCommon) closeConn() {
 	_ = ipc.conn.Close()
+	ipc.writerMu.Lock()
+	if ipc.frames != nil {
+		ipc.frames.close()
+	}
+	ipc.writerMu.Unlock()
 	ipc.mu.Lock()
 	pending := ipc.pendingCalls
 	ipc.pendingCalls = make(map[int64]*pendingCall)
 	ipc.mu.Unlock()
+	ipc.pendingCallsChanged(-len(pending))
 	for _, call := range pending {
 		call.resultChan <- callResult{err: fmt.Errorf("call cancelled due to ipc termination")}
 		close(call.resultChan)
@@ -1,11 +1,28 @@
 package golang
 
 import (
+	"context"
+	"net"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// newTestPair connects two ipcCommon peers over an in-memory pipe.
+func newTestPair(t *testing.T, optsA, optsB *Options, apisA, apisB []any) (*ipcCommon, *ipcCommon) {
+	connA, connB := net.Pipe()
+	a := newIpcCommon(context.Background(), optsA, apisA)
+	b := newIpcCommon(context.Background(), optsB, apisB)
+	a.conn, b.conn = connA, connB
+	go a.readConn()
+	go b.readConn()
+	t.Cleanup(func() {
+		a.closeConn()
+		b.closeConn()
+	})
+	return a, b
+}
+
 type testEndpoint struct{}
 
 func (e *testEndpoint) Hello(name string) (string, error) {
@@ -0,0 +1,174 @@
+package golang
+
+import (
+	"fmt"
+	"slices"
+)
+
+// maxCauseDepth limits the length of the cause chain sent to the remote process.
+const maxCauseDepth = 16
+
+// Error is an error with a stable code which is preserved across the process boundary.
+// Sentinels annotated with `// kittenipc:error` are generated by kitcom for the remote side,
+// so errors.Is works on both ends.
+type Error struct {
+	Code    string
+	Message string
+	Details map[string]any
+	// causes are the errors wrapped with %w by Errorf
+	causes []error
+}
+
+func NewError(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Message
+}
+
+// Is reports whether target is an Error with the same code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Unwrap returns the errors wrapped by Errorf.
+func (e *Error) Unwrap() []error {
+	return e.causes
+}
+
+// Errorf returns a copy of the error with a message formatted like fmt.Errorf. Errors formatted with %w
+// are wrapped and sent to the remote process as the cause. The copy matches e with errors.Is.
+func (e *Error) Errorf(format string, args ...any) *Error {
+	err := fmt.Errorf(format, args...)
+	var causes []error
+	switch wrapped := err.(type) {
+	case interface{ Unwrap() error }:
+		causes = []error{wrapped.Unwrap()}
+	case interface{ Unwrap() []error }:
+		causes = wrapped.Unwrap()
+	}
+	return &Error{Code: e.Code, Message: err.Error(), Details: e.Details, causes: causes}
+}
+
+// WithDetails returns a copy of the error carrying details. The copy matches e with errors.Is.
+func (e *Error) WithDetails(details map[string]any) *Error {
+	return &Error{Code: e.Code, Message: e.Message, Details: details, causes: e.causes}
+}
+
+var (
+	ErrMethodNotFound  = NewError("MethodNotFound", "method not found")
+	ErrInvalidArgument = NewError("InvalidArgument", "invalid argument")
+	ErrInternal        = NewError("Internal", "internal error")
+	// ErrMessageTooLarge is returned for calls and responses over Options.MaxMessageSize of the receiving side.
+	ErrMessageTooLarge = NewError("MessageTooLarge", "message too large")
+)
+
+// RemoteError is an error returned by the remote process.
+type RemoteError struct {
+	Code    string
+	Message string
+	Details map[string]any
+	Cause   *RemoteError
+	// Causes are set instead of Cause when the remote error wrapped several errors.
+	Causes []*RemoteError
+	// Stack is the goroutine or JS stack of the failed call, sent by the remote process when
+	// Options.PropagateStacks is enabled there.
+	Stack string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("remote error: %s", e.Message)
+}
+
+func (e *RemoteError) Unwrap() []error {
+	if e.Cause != nil {
+		return []error{e.Cause}
+	}
+	causes := make([]error, len(e.Causes))
+	for i, cause := range e.Causes {
+		causes[i] = cause
+	}
+	return causes
+}
+
+// Is reports whether target is an Error with the same code as the remote one.
+func (e *RemoteError) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && e.Code != "" && t.Code == e.Code
+}
+
+func newErrorPayload(err error) *ErrorPayload {
+	return newErrorPayloadDepth(err, 0)
+}
+
+// stackError attaches the stack of a recovered panic to an error.
+type stackError struct {
+	error
+	stack string
+}
+
+func (e *stackError) Unwrap() error {
+	return e.error
+}
+
+func newErrorPayloadDepth(err error, depth int) *ErrorPayload {
+	if stackErr, ok := err.(*stackError); ok {
+		payload := newErrorPayloadDepth(stackErr.error, depth)
+		payload.Stack = stackErr.stack
+		return payload
+	}
+
+	payload := &ErrorPayload{Message: err.Error()}
+
+	// wrappers get no code of their own, the coded error is found in the cause chain on the remote side
+	switch e := err.(type) {
+	case *Error:
+		payload.Code = e.Code
+		payload.Details = e.Details
+	case *RemoteError:
+		payload.Code = e.Code
+		payload.Details = e.Details
+	}
+
+	if depth >= maxCauseDepth {
+		return payload
+	}
+	var causes []error
+	switch wrapped := err.(type) {
+	case interface{ Unwrap() error }:
+		causes = []error{wrapped.Unwrap()}
+	case interface{ Unwrap() []error }:
+		causes = wrapped.Unwrap()
+	}
+	causes = slices.DeleteFunc(causes, func(cause error) bool { return cause == nil })
+	if len(causes) == 1 {
+		payload.Cause = newErrorPayloadDepth(causes[0], depth+1)
+	} else {
+		for _, cause := range causes {
+			payload.Causes = append(payload.Causes, newErrorPayloadDepth(cause, depth+1))
+		}
+	}
+
+	return payload
+}
+
+func (p *ErrorPayload) remoteError() *RemoteError {
+	remoteErr := &RemoteError{
+		Code:    p.Code,
+		Message: p.Message,
+		Details: p.Details,
+		Stack:   p.Stack,
+	}
+	if p.Cause != nil {
+		remoteErr.Cause = p.Cause.remoteError()
+	}
+	for _, cause := range p.Causes {
+		remoteErr.Causes = append(remoteErr.Causes, cause.remoteError())
+	}
+	return remoteErr
+}
@@ -0,0 +1,129 @@
+package golang
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestNotFound = NewError("NotFound", "not found")
+
+type errorsEndpoint struct{}
+
+func (e *errorsEndpoint) Find(id int) (string, error) {
+	return "", fmt.Errorf("find: %w", errTestNotFound.Errorf("item %d not found", id).WithDetails(map[string]any{"id": id}))
+}
+
+func (e *errorsEndpoint) Crash() error {
+	panic("boom")
+}
+
+func TestErrorPayload(t *testing.T) {
+	t.Run("code, details and cause chain", func(t *testing.T) {
+		err := fmt.Errorf("outer: %w", fmt.Errorf("middle: %w", errTestNotFound.WithDetails(map[string]any{"k": "v"})))
+		payload := newErrorPayload(err)
+		assert.Empty(t, payload.Code)
+		assert.Equal(t, "outer: middle: not found", payload.Message)
+		assert.Nil(t, payload.Details)
+		require.NotNil(t, payload.Cause)
+		assert.Empty(t, payload.Cause.Code)
+		require.NotNil(t, payload.Cause.Cause)
+		assert.Equal(t, "NotFound", payload.Cause.Cause.Code)
+		assert.Equal(t, "not found", payload.Cause.Cause.Message)
+		assert.Equal(t, map[string]any{"k": "v"}, payload.Cause.Cause.Details)
+		assert.Nil(t, payload.Cause.Cause.Cause)
+	})
+
+	t.Run("several causes", func(t *testing.T) {
+		err := errors.Join(errors.New("plain"), ErrInternal.Errorf("read: %w, %w", io.EOF, errTestNotFound))
+		payload := newErrorPayload(err)
+		assert.Nil(t, payload.Cause)
+		require.Len(t, payload.Causes, 2)
+		assert.Equal(t, "plain", payload.Causes[0].Message)
+		assert.Equal(t, "Internal", payload.Causes[1].Code)
+		require.Len(t, payload.Causes[1].Causes, 2)
+		assert.Equal(t, "EOF", payload.Causes[1].Causes[0].Message)
+		assert.Equal(t, "NotFound", payload.Causes[1].Causes[1].Code)
+
+		remoteErr := payload.remoteError()
+		assert.ErrorIs(t, remoteErr, ErrInternal)
+		assert.ErrorIs(t, remoteErr, errTestNotFound)
+	})
+
+	t.Run("plain error has no code", func(t *testing.T) {
+		payload := newErrorPayload(errors.New("plain"))
+		assert.Empty(t, payload.Code)
+		assert.Equal(t, "plain", payload.Message)
+	})
+
+	t.Run("errorf wraps causes", func(t *testing.T) {
+		err := ErrInternal.Errorf("read config: %w", io.EOF)
+		assert.EqualError(t, err, "read config: EOF")
+		assert.ErrorIs(t, err, ErrInternal)
+		assert.ErrorIs(t, err, io.EOF)
+
+		err = ErrInternal.Errorf("read %d: %v", 1, io.EOF).WithDetails(map[string]any{"k": "v"})
+		assert.EqualError(t, err, "read 1: EOF")
+		assert.NotErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("remote error matches sentinel", func(t *testing.T) {
+		remoteErr := newErrorPayload(errTestNotFound).remoteError()
+		assert.ErrorIs(t, remoteErr, errTestNotFound)
+		assert.NotErrorIs(t, remoteErr, ErrInternal)
+		assert.EqualError(t, remoteErr, "remote error: not found")
+	})
+}
+
+func TestRemoteErrors(t *testing.T) {
+	a, _ := newTestPair(t, nil, nil, nil, []any{&errorsEndpoint{}})
+
+	t.Run("sentinel survives the boundary", func(t *testing.T) {
+		_, err := a.Call("errorsEndpoint.Find", 42)
+		assert.ErrorIs(t, err, errTestNotFound)
+
+		var remoteErr *RemoteError
+		require.ErrorAs(t, err, &remoteErr)
+		assert.Empty(t, remoteErr.Code)
+		assert.Equal(t, "remote error: find: item 42 not found", remoteErr.Error())
+		require.NotNil(t, remoteErr.Cause)
+		assert.Equal(t, "NotFound", remoteErr.Cause.Code)
+		assert.Equal(t, map[string]any{"id": float64(42)}, remoteErr.Cause.Details)
+		assert.Equal(t, "item 42 not found", remoteErr.Cause.Message)
+	})
+
+	t.Run("library errors carry codes", func(t *testing.T) {
+		_, err := a.Call("errorsEndpoint.Unknown")
+		assert.ErrorIs(t, err, ErrMethodNotFound)
+
+		_, err = a.Call("errorsEndpoint.Find", 1, 2)
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+
+		_, err = a.Call("errorsEndpoint.Crash")
+		assert.ErrorIs(t, err, ErrInternal)
+	})
+}
+
+func TestPropagateStacks(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		a, _ := newTestPair(t, nil, nil, nil, []any{&errorsEndpoint{}})
+		_, err := a.Call("errorsEndpoint.Crash")
+		var remoteErr *RemoteError
+		require.ErrorAs(t, err, &remoteErr)
+		assert.Empty(t, remoteErr.Stack)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		a, _ := newTestPair(t, nil, &Options{PropagateStacks: true}, nil, []any{&errorsEndpoint{}})
+		_, err := a.Call("errorsEndpoint.Crash")
+		assert.ErrorIs(t, err, ErrInternal)
+		var remoteErr *RemoteError
+		require.ErrorAs(t, err, &remoteErr)
+		assert.Contains(t, remoteErr.Stack, "(*errorsEndpoint).Crash")
+		assert.Nil(t, remoteErr.Cause)
+	})
+}
@@ -0,0 +1,67 @@
+package golang
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// ChildExitError is returned by ParentIPC when the child fails.
+type ChildExitError struct {
+	// Err is the error of Launcher.Wait, usually *exec.ExitError.
+	Err error
+	// ExitCode is the exit code of the child, -1 if it was killed by a signal or could not be waited for.
+	ExitCode int
+	// Signal is the signal which killed the child, if any.
+	Signal syscall.Signal
+	// Requested is true if the child exited after ParentIPC asked it to stop.
+	Requested bool
+	// Duration is how long the child ran.
+	Duration time.Duration
+	// Stderr holds the last lines the child wrote to stderr, see Options.StderrTail.
+	Stderr string
+}
+
+func newChildExitError(err error, requested bool, duration time.Duration, stderr string) *ChildExitError {
+	e := &ChildExitError{Err: err, Requested: requested, Duration: duration, Stderr: stderr}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		e.ExitCode = exitErr.ExitCode()
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			e.Signal = ws.Signal()
+		}
+	default:
+		e.ExitCode = -1
+	}
+	return e
+}
+
+func (e *ChildExitError) Error() string {
+	var msg string
+	var exitErr *exec.ExitError
+	switch {
+	case e.Signal != 0:
+		msg = fmt.Sprintf("child killed by signal: %v", e.Signal)
+	case e.Err == nil || errors.As(e.Err, &exitErr):
+		msg = fmt.Sprintf("child exited with code %d", e.ExitCode)
+	default:
+		msg = fmt.Sprintf("child wait: %v", e.Err)
+	}
+	if e.Stderr != "" {
+		msg += "\nstderr:\n" + e.Stderr
+	}
+	return msg
+}
+
+func (e *ChildExitError) Unwrap() error {
+	return e.Err
+}
+
+// stopped reports whether the child was stopped by ParentIPC, which is not an error.
+func (e *ChildExitError) stopped() bool {
+	return e.Requested && (e.Signal == syscall.SIGINT || e.Signal == syscall.SIGKILL)
+}
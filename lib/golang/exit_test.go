@@ -0,0 +1,74 @@
+package golang
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChildExitError(t *testing.T) {
+	t.Run("exit code", func(t *testing.T) {
+		err := exec.Command("sh", "-c", "exit 3").Run()
+		exitErr := newChildExitError(err, false, time.Second, "oops\n")
+		assert.Equal(t, 3, exitErr.ExitCode)
+		assert.Zero(t, exitErr.Signal)
+		assert.False(t, exitErr.stopped())
+		assert.EqualError(t, exitErr, "child exited with code 3\nstderr:\noops\n")
+	})
+
+	t.Run("signal", func(t *testing.T) {
+		err := exec.Command("sh", "-c", "kill -KILL $$").Run()
+		exitErr := newChildExitError(err, true, time.Second, "")
+		assert.Equal(t, -1, exitErr.ExitCode)
+		assert.Equal(t, syscall.SIGKILL, exitErr.Signal)
+		assert.True(t, exitErr.stopped())
+		assert.EqualError(t, exitErr, "child killed by signal: killed")
+	})
+
+	t.Run("wait failure", func(t *testing.T) {
+		err := errors.New("broken")
+		exitErr := newChildExitError(err, false, time.Second, "")
+		assert.Equal(t, -1, exitErr.ExitCode)
+		assert.ErrorIs(t, exitErr, err)
+		assert.EqualError(t, exitErr, "child wait: broken")
+	})
+
+	t.Run("clean exit", func(t *testing.T) {
+		exitErr := newChildExitError(nil, false, time.Second, "")
+		assert.Zero(t, exitErr.ExitCode)
+		assert.EqualError(t, exitErr, "child exited with code 0")
+	})
+}
+
+func TestIPCError(t *testing.T) {
+	err1 := errors.New("one")
+	err2 := errors.New("two")
+	err := &IPCError{Errs: []error{err1, err2}}
+	assert.ErrorIs(t, err, err1)
+	assert.ErrorIs(t, err, err2)
+	assert.EqualError(t, err, "ipc error: one\ntwo")
+}
+
+func TestWaitIPCError(t *testing.T) {
+	launcher := &inProcessLauncher{}
+	p, err := NewParentWithLauncher(context.Background(), launcher, &Options{SocketHandoff: SocketHandoffEnv})
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	t.Cleanup(func() { _ = launcher.peer.Stop() })
+
+	_, err = launcher.peer.conn.Write([]byte("garbage\n"))
+	require.NoError(t, err)
+
+	err = p.Wait()
+	var ipcErr *IPCError
+	require.ErrorAs(t, err, &ipcErr)
+	assert.ErrorContains(t, ipcErr, "unmarshal message")
+	var exitErr *ChildExitError
+	assert.False(t, errors.As(err, &exitErr))
+}
@@ -0,0 +1,465 @@
+package golang
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFrameSize = 1 << 20 // 1 MB
+	minFrameSize     = 256
+	// writeBufferSize is the amount of frames batched into a single write.
+	writeBufferSize = 64 << 10
+	// maxPooledBuffer keeps buffers of big messages out of the pool.
+	maxPooledBuffer = 64 << 10
+	// chunkOverhead is an upper bound of the chunk frame size without its data.
+	chunkOverhead = 64
+)
+
+var errWriterClosed = errors.New("connection closed")
+
+// encodeBuffer is a buffer for encoding outgoing messages along with an encoder writing to it.
+type encodeBuffer struct {
+	bytes.Buffer
+	enc *json.Encoder
+}
+
+// bufferPool holds buffers for encoding outgoing messages.
+var bufferPool = sync.Pool{New: func() any {
+	buf := new(encodeBuffer)
+	buf.enc = json.NewEncoder(&buf.Buffer)
+	return buf
+}}
+
+func getBuffer() *encodeBuffer {
+	buf := bufferPool.Get().(*encodeBuffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *encodeBuffer) {
+	if buf.Cap() <= maxPooledBuffer {
+		bufferPool.Put(buf)
+	}
+}
+
+// outMessagePool holds queued messages with their done channels, which are empty once write returns.
+var outMessagePool = sync.Pool{New: func() any { return &outMessage{done: make(chan error, 1)} }}
+
+// chunkFrame carries a part of a message larger than the frame size. Chunks of a message share
+// the id, which is unrelated to call ids, and arrive in order. The last one completes the message.
+type chunkFrame struct {
+	Type MsgType `json:"type"`
+	Id   int64   `json:"id"`
+	Data []byte  `json:"data"`
+	Last bool    `json:"last,omitempty"`
+}
+
+// frame is any frame received, a whole message or a chunk.
+type frame struct {
+	Message
+	Data []byte `json:"data,omitempty"`
+	Last bool   `json:"last,omitempty"`
+}
+
+// outMessage is an encoded message waiting for the writer, sent whole or in chunks.
+type outMessage struct {
+	data    []byte // without the trailing newline
+	chunkId int64  // zero for messages sent whole
+	off     int
+	lane    Lane
+	queued  time.Time
+	started bool
+	done    chan error
+}
+
+// frameWriter owns writing to the connection. Messages are sent in frames of at most frameSize bytes.
+// Frames of higher lanes go first, and frames of queued messages of a lane take turns,
+// so small messages are not stuck behind big ones.
+//
+// When batching, frames are collected in a buffer, which is written when it fills up or when the queue stays empty
+// after the writer yields once, so messages queued by concurrent calls share a write.
+type frameWriter struct {
+	w           io.Writer
+	buf         *bufio.Writer // nil when not batching
+	frameSize   int
+	nextChunkId int64
+	// onDequeue is called when the first frame of a message is about to be written, if set
+	onDequeue func(lane Lane, delay time.Duration)
+	// onQueueChanged is called when messages waiting for their first frame are added or removed, if set
+	onQueueChanged func(lane Lane, delta int)
+
+	// unflushed are written messages waiting for the buffer to be flushed, used by the run goroutine only
+	unflushed []*outMessage
+
+	mu     sync.Mutex
+	queues [laneCount][]*outMessage
+	wake   chan struct{}
+	closed bool
+	err    error
+}
+
+func newFrameWriter(w io.Writer, frameSize int, batch bool, onDequeue func(Lane, time.Duration), onQueueChanged func(Lane, int)) *frameWriter {
+	fw := &frameWriter{
+		w:              w,
+		frameSize:      frameSize,
+		onDequeue:      onDequeue,
+		onQueueChanged: onQueueChanged,
+		wake:           make(chan struct{}, 1),
+	}
+	if batch {
+		fw.buf = bufio.NewWriterSize(w, writeBufferSize)
+	}
+	go fw.run()
+	return fw
+}
+
+// write queues the encoded message in the lane and waits until it is written. If ctx is done before the first frame
+// of the message is taken, the message is dropped from the queue. Once started, the message is written to the end.
+func (fw *frameWriter) write(ctx context.Context, data []byte, lane Lane) error {
+	msg := outMessagePool.Get().(*outMessage)
+	*msg = outMessage{data: data, lane: lane, queued: time.Now(), done: msg.done}
+	defer func() {
+		msg.data = nil
+		outMessagePool.Put(msg)
+	}()
+
+	fw.mu.Lock()
+	if fw.closed {
+		err := fw.err
+		fw.mu.Unlock()
+		return err
+	}
+	if fw.frameSize > 0 && len(data) > fw.frameSize {
+		fw.nextChunkId++
+		msg.chunkId = fw.nextChunkId
+	}
+	fw.queues[lane] = append(fw.queues[lane], msg)
+	fw.mu.Unlock()
+	fw.queueChanged(lane, 1)
+
+	select {
+	case fw.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case err := <-msg.done:
+		return err
+	case <-ctx.Done():
+	}
+	if fw.dequeue(msg) {
+		return ctx.Err()
+	}
+	return <-msg.done
+}
+
+// dequeue removes the message from its queue unless it has started or the writer has failed it.
+func (fw *frameWriter) dequeue(msg *outMessage) bool {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if msg.started {
+		return false
+	}
+	queue := fw.queues[msg.lane]
+	i := slices.Index(queue, msg)
+	if i < 0 {
+		return false
+	}
+	fw.queues[msg.lane] = slices.Delete(queue, i, i+1)
+	fw.queueChanged(msg.lane, -1)
+	return true
+}
+
+// close fails queued messages and stops the writer.
+func (fw *frameWriter) close() {
+	fw.fail(errWriterClosed)
+}
+
+func (fw *frameWriter) fail(err error) {
+	fw.mu.Lock()
+	if fw.closed {
+		fw.mu.Unlock()
+		return
+	}
+	fw.closed = true
+	fw.err = err
+	queues := fw.queues
+	fw.queues = [laneCount][]*outMessage{}
+	fw.mu.Unlock()
+
+	for _, queue := range queues {
+		for _, msg := range queue {
+			if !msg.started {
+				fw.queueChanged(msg.lane, -1)
+			}
+			msg.done <- err
+		}
+	}
+	select {
+	case fw.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next takes the first message of the highest non-empty lane. It reports whether the message is taken for the first time.
+func (fw *frameWriter) next() (*outMessage, bool) {
+	for _, lane := range lanePriority {
+		if queue := fw.queues[lane]; len(queue) > 0 {
+			msg := queue[0]
+			queue[0] = nil
+			if len(queue) == 1 {
+				// keeps the capacity for the next messages
+				fw.queues[lane] = queue[:0]
+			} else {
+				fw.queues[lane] = queue[1:]
+			}
+			first := !msg.started
+			msg.started = true
+			return msg, first
+		}
+	}
+	return nil, false
+}
+
+func (fw *frameWriter) queueChanged(lane Lane, delta int) {
+	if fw.onQueueChanged != nil {
+		fw.onQueueChanged(lane, delta)
+	}
+}
+
+func (fw *frameWriter) run() {
+	defer func() {
+		fw.mu.Lock()
+		err := fw.err
+		fw.mu.Unlock()
+		fw.complete(err)
+	}()
+
+	yielded := false
+	for {
+		fw.mu.Lock()
+		if fw.closed {
+			fw.mu.Unlock()
+			return
+		}
+		msg, first := fw.next()
+		fw.mu.Unlock()
+		if msg == nil {
+			if fw.buf != nil && fw.buf.Buffered() > 0 && !yielded {
+				// Callers woken by the last flush get a chance to queue messages into this batch
+				yielded = true
+				runtime.Gosched()
+				continue
+			}
+			yielded = false
+			if err := fw.flush(); err != nil {
+				fw.fail(err)
+				return
+			}
+			<-fw.wake
+			continue
+		}
+
+		if first {
+			fw.queueChanged(msg.lane, -1)
+			if fw.onDequeue != nil {
+				fw.onDequeue(msg.lane, time.Since(msg.queued))
+			}
+		}
+		data, last, err := fw.nextFrame(msg)
+		if err == nil {
+			err = fw.writeFrame(data)
+		}
+		if err != nil {
+			err = fmt.Errorf("write message: %w", err)
+			msg.done <- err
+			fw.fail(err)
+			return
+		}
+		if last {
+			fw.unflushed = append(fw.unflushed, msg)
+			if fw.buf == nil {
+				fw.complete(nil)
+			}
+			continue
+		}
+
+		fw.mu.Lock()
+		if fw.closed {
+			fw.mu.Unlock()
+			msg.done <- fw.err
+			return
+		}
+		fw.queues[msg.lane] = append(fw.queues[msg.lane], msg)
+		fw.mu.Unlock()
+	}
+}
+
+// writeFrame writes the frame or adds it to the batch, flushing the batch first if the frame does not fit.
+func (fw *frameWriter) writeFrame(data []byte) error {
+	if fw.buf == nil {
+		_, err := fw.w.Write(data)
+		return err
+	}
+	if len(data) > fw.buf.Available() && fw.buf.Buffered() > 0 {
+		if err := fw.buf.Flush(); err != nil {
+			return err
+		}
+		fw.complete(nil)
+	}
+	_, err := fw.buf.Write(data)
+	return err
+}
+
+// flush writes the batch and completes messages written with it.
+func (fw *frameWriter) flush() error {
+	if fw.buf != nil && fw.buf.Buffered() > 0 {
+		if err := fw.buf.Flush(); err != nil {
+			return fmt.Errorf("write message: %w", err)
+		}
+	}
+	fw.complete(nil)
+	return nil
+}
+
+// complete passes the result to unflushed messages.
+func (fw *frameWriter) complete(err error) {
+	for _, msg := range fw.unflushed {
+		msg.done <- err
+	}
+	clear(fw.unflushed)
+	fw.unflushed = fw.unflushed[:0]
+}
+
+// nextFrame returns the next frame of msg with the trailing newline.
+func (fw *frameWriter) nextFrame(msg *outMessage) ([]byte, bool, error) {
+	if msg.chunkId == 0 {
+		return append(msg.data, '\n'), true, nil
+	}
+	chunkSize := (fw.frameSize - chunkOverhead) / 4 * 3
+	end := min(msg.off+chunkSize, len(msg.data))
+	chunk := chunkFrame{Type: MsgChunk, Id: msg.chunkId, Data: msg.data[msg.off:end], Last: end == len(msg.data)}
+	msg.off = end
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return nil, false, err
+	}
+	return append(data, '\n'), chunk.Last, nil
+}
+
+// maxChunkedMessages limits chunked messages being assembled at once. Writers send chunks of queued messages
+// in turns, so it takes this many concurrent big messages to reach it.
+const maxChunkedMessages = 1024
+
+var errTooManyChunked = fmt.Errorf("more than %d chunked messages in progress", maxChunkedMessages)
+
+// chunkAssembler puts chunked messages together, skipping those over the message size limit.
+// Messages being assembled share the limit, so a peer which never finishes them cannot exceed it.
+type chunkAssembler struct {
+	limit    int
+	total    int // bytes of messages being assembled
+	messages map[int64]*chunkedMessage
+}
+
+type chunkedMessage struct {
+	data      []byte
+	oversized *oversizedMessage
+}
+
+func newChunkAssembler(limit int) *chunkAssembler {
+	return &chunkAssembler{limit: limit, messages: make(map[int64]*chunkedMessage)}
+}
+
+func (a *chunkAssembler) message(id int64) (*chunkedMessage, error) {
+	msg := a.messages[id]
+	if msg == nil {
+		if len(a.messages) >= maxChunkedMessages {
+			return nil, errTooManyChunked
+		}
+		msg = &chunkedMessage{}
+		a.messages[id] = msg
+	}
+	return msg, nil
+}
+
+// add appends the chunk to its message. Once the last chunk arrives, it returns the message or describes it if it is over the limit.
+func (a *chunkAssembler) add(f *frame) ([]byte, *oversizedMessage, bool, error) {
+	msg, err := a.message(f.Id)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	switch {
+	case msg.oversized != nil:
+		msg.oversized.size += len(f.Data)
+		msg.oversized.prefix = appendPrefix(msg.oversized.prefix, f.Data)
+	case len(msg.data)+len(f.Data) > a.limit || a.total+len(f.Data) > a.limit:
+		a.setOversized(msg)
+		msg.oversized.size += len(f.Data)
+		msg.oversized.prefix = appendPrefix(msg.oversized.prefix, f.Data)
+	default:
+		msg.data = append(msg.data, f.Data...)
+		a.total += len(f.Data)
+	}
+	if !f.Last {
+		return nil, nil, false, nil
+	}
+	delete(a.messages, f.Id)
+	a.total -= len(msg.data)
+	return msg.data, msg.oversized, true, nil
+}
+
+// skip marks the message of a chunk frame which was too large to read as oversized, keeping the beginning
+// of its data if it is the first chunk. If the frame was the last one, it describes the message.
+func (a *chunkAssembler) skip(id int64, skipped *oversizedMessage) (*oversizedMessage, bool, error) {
+	msg, err := a.message(id)
+	if err != nil {
+		return nil, false, err
+	}
+	if msg.oversized == nil {
+		a.setOversized(msg)
+	}
+	msg.oversized.size += skipped.size
+	msg.oversized.prefix = appendPrefix(msg.oversized.prefix, chunkDataPrefix(skipped.prefix))
+	// Writers put the last flag at the end of the frame
+	if !bytes.HasSuffix(skipped.suffix, []byte(`"last":true}`)) {
+		return nil, false, nil
+	}
+	delete(a.messages, id)
+	return msg.oversized, true, nil
+}
+
+// setOversized drops data collected for msg, keeping its beginning.
+func (a *chunkAssembler) setOversized(msg *chunkedMessage) {
+	msg.oversized = &oversizedMessage{size: len(msg.data), prefix: appendPrefix(nil, msg.data)}
+	a.total -= len(msg.data)
+	msg.data = nil
+}
+
+// chunkDataPrefix decodes what it can of the data of a chunk frame from its beginning.
+func chunkDataPrefix(framePrefix []byte) []byte {
+	_, encoded, ok := bytes.Cut(framePrefix, []byte(`"data":"`))
+	if !ok {
+		return nil
+	}
+	if end := bytes.IndexByte(encoded, '"'); end >= 0 {
+		encoded = encoded[:end]
+	}
+	encoded = encoded[:len(encoded)/4*4]
+	data := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	n, err := base64.StdEncoding.Decode(data, encoded)
+	if err != nil {
+		return nil
+	}
+	return data[:n]
+}
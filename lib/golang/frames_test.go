@@ -0,0 +1,334 @@
+package golang
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedWriter records written frames. The first write blocks until the gate is opened.
+type gatedWriter struct {
+	started chan struct{}
+	gate    chan struct{}
+	once    sync.Once
+
+	mu     sync.Mutex
+	frames [][]byte
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{started: make(chan struct{}), gate: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(data []byte) (int, error) {
+	w.once.Do(func() {
+		close(w.started)
+		<-w.gate
+	})
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.frames = append(w.frames, bytes.Clone(data))
+	return len(data), nil
+}
+
+// assemble decodes written frames back into messages.
+func assemble(t *testing.T, frames [][]byte) []string {
+	var messages []string
+	chunks := newChunkAssembler(defaultMaxMessageSize)
+	for _, data := range frames {
+		var f frame
+		require.NoError(t, json.Unmarshal(data, &f))
+		if f.Type != MsgChunk {
+			messages = append(messages, string(bytes.TrimSuffix(data, []byte{'\n'})))
+			continue
+		}
+		msg, oversized, complete, err := chunks.add(&f)
+		require.NoError(t, err)
+		require.Nil(t, oversized)
+		if complete {
+			messages = append(messages, string(msg))
+		}
+	}
+	return messages
+}
+
+func TestFrameWriter(t *testing.T) {
+	big := `{"type":2,"id":1,"result":["` + strings.Repeat("x", 2000) + `"]}`
+	small := `{"type":1,"id":2,"method":"A.B"}`
+
+	t.Run("chunks interleave", func(t *testing.T) {
+		w := newGatedWriter()
+		fw := newFrameWriter(w, 256, false, nil, nil)
+		defer fw.close()
+
+		var wg sync.WaitGroup
+		wg.Go(func() { assert.NoError(t, fw.write(context.Background(), []byte(big), LaneNormal)) })
+		<-w.started
+		wg.Go(func() { assert.NoError(t, fw.write(context.Background(), []byte(small), LaneNormal)) })
+		require.Eventually(t, func() bool {
+			fw.mu.Lock()
+			defer fw.mu.Unlock()
+			return len(fw.queues[LaneNormal]) == 1
+		}, time.Second, time.Millisecond)
+		close(w.gate)
+		wg.Wait()
+
+		require.Greater(t, len(w.frames), 2)
+		for _, data := range w.frames {
+			assert.LessOrEqual(t, len(data)-1, 256)
+		}
+		assert.Equal(t, small+"\n", string(w.frames[1]))
+		assert.Equal(t, []string{small, big}, assemble(t, w.frames))
+	})
+
+	t.Run("lanes", func(t *testing.T) {
+		w := newGatedWriter()
+		var delays []Lane
+		var queuedMu sync.Mutex
+		var queued [laneCount]int
+		queueLength := func(lane Lane) int {
+			queuedMu.Lock()
+			defer queuedMu.Unlock()
+			return queued[lane]
+		}
+		fw := newFrameWriter(w, 256, false,
+			func(lane Lane, delay time.Duration) { delays = append(delays, lane) },
+			func(lane Lane, delta int) {
+				queuedMu.Lock()
+				defer queuedMu.Unlock()
+				queued[lane] += delta
+			})
+		defer fw.close()
+
+		control := `{"type":1,"id":3,"method":"A.Ping"}`
+		var wg sync.WaitGroup
+		wg.Go(func() { assert.NoError(t, fw.write(context.Background(), []byte(big), LaneBulk)) })
+		<-w.started
+		wg.Go(func() { assert.NoError(t, fw.write(context.Background(), []byte(small), LaneNormal)) })
+		wg.Go(func() { assert.NoError(t, fw.write(context.Background(), []byte(control), LaneControl)) })
+		require.Eventually(t, func() bool {
+			fw.mu.Lock()
+			defer fw.mu.Unlock()
+			return len(fw.queues[LaneNormal]) == 1 && len(fw.queues[LaneControl]) == 1
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, 0, queueLength(LaneBulk), "started messages are not queued")
+		assert.Equal(t, 1, queueLength(LaneNormal))
+		assert.Equal(t, 1, queueLength(LaneControl))
+		close(w.gate)
+		wg.Wait()
+		assert.Equal(t, 0, queueLength(LaneNormal))
+		assert.Equal(t, 0, queueLength(LaneControl))
+
+		assert.Equal(t, control+"\n", string(w.frames[1]))
+		assert.Equal(t, small+"\n", string(w.frames[2]))
+		assert.Equal(t, []string{control, small, big}, assemble(t, w.frames))
+		assert.Equal(t, []Lane{LaneBulk, LaneControl, LaneNormal}, delays)
+	})
+
+	t.Run("batching", func(t *testing.T) {
+		w := newGatedWriter()
+		fw := newFrameWriter(w, 256, true, nil, nil)
+		defer fw.close()
+
+		var wg sync.WaitGroup
+		wg.Go(func() { assert.NoError(t, fw.write(context.Background(), []byte(small), LaneNormal)) })
+		<-w.started
+		for range 3 {
+			wg.Go(func() { assert.NoError(t, fw.write(context.Background(), []byte(small), LaneNormal)) })
+		}
+		require.Eventually(t, func() bool {
+			fw.mu.Lock()
+			defer fw.mu.Unlock()
+			return len(fw.queues[LaneNormal]) == 3
+		}, time.Second, time.Millisecond)
+		close(w.gate)
+		wg.Wait()
+
+		line := small + "\n"
+		require.Len(t, w.frames, 2)
+		assert.Equal(t, []string{line, line + line + line}, []string{string(w.frames[0]), string(w.frames[1])})
+	})
+
+	t.Run("chunking disabled", func(t *testing.T) {
+		w := newGatedWriter()
+		close(w.gate)
+		fw := newFrameWriter(w, 0, false, nil, nil)
+		defer fw.close()
+
+		require.NoError(t, fw.write(context.Background(), []byte(big), LaneNormal))
+		assert.Equal(t, [][]byte{[]byte(big + "\n")}, w.frames)
+	})
+
+	t.Run("cancelled while queued", func(t *testing.T) {
+		w := newGatedWriter()
+		var queuedMu sync.Mutex
+		var queued int
+		fw := newFrameWriter(w, 256, false, nil, func(lane Lane, delta int) {
+			queuedMu.Lock()
+			defer queuedMu.Unlock()
+			queued += delta
+		})
+		defer fw.close()
+
+		var wg sync.WaitGroup
+		wg.Go(func() { assert.NoError(t, fw.write(context.Background(), []byte(big), LaneBulk)) })
+		<-w.started
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, fw.write(ctx, []byte(small), LaneNormal), context.DeadlineExceeded)
+		queuedMu.Lock()
+		assert.Zero(t, queued)
+		queuedMu.Unlock()
+
+		close(w.gate)
+		wg.Wait()
+		assert.Equal(t, []string{big}, assemble(t, w.frames))
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		fw := newFrameWriter(newGatedWriter(), 256, false, nil, nil)
+		fw.close()
+		assert.ErrorIs(t, fw.write(context.Background(), []byte(small), LaneNormal), errWriterClosed)
+	})
+}
+
+func TestChunkAssembler(t *testing.T) {
+	chunk := func(id int64, data string, last bool) *frame {
+		return &frame{Message: Message{Type: MsgChunk, Id: id}, Data: []byte(data), Last: last}
+	}
+
+	t.Run("message over limit", func(t *testing.T) {
+		a := newChunkAssembler(10)
+		_, _, complete, err := a.add(chunk(1, `{"type":1,`, false))
+		require.NoError(t, err)
+		assert.False(t, complete)
+		msg, oversized, complete, err := a.add(chunk(1, `"id":5}`, true))
+		require.NoError(t, err)
+		assert.True(t, complete)
+		assert.Nil(t, msg)
+		require.NotNil(t, oversized)
+		assert.Equal(t, 17, oversized.size)
+		typ, id, ok := messageHeader(oversized.prefix)
+		assert.True(t, ok)
+		assert.Equal(t, MsgCall, typ)
+		assert.Equal(t, int64(5), id)
+		assert.Empty(t, a.messages)
+		assert.Zero(t, a.total)
+	})
+
+	t.Run("messages share the limit", func(t *testing.T) {
+		a := newChunkAssembler(10)
+		_, _, _, err := a.add(chunk(1, "123456", false))
+		require.NoError(t, err)
+		_, _, _, err = a.add(chunk(2, "123456", false))
+		require.NoError(t, err)
+		assert.Equal(t, 6, a.total)
+
+		_, oversized, complete, err := a.add(chunk(2, "7", true))
+		require.NoError(t, err)
+		assert.True(t, complete)
+		require.NotNil(t, oversized)
+		msg, oversized, complete, err := a.add(chunk(1, "7", true))
+		require.NoError(t, err)
+		assert.True(t, complete)
+		assert.Nil(t, oversized)
+		assert.Equal(t, "1234567", string(msg))
+		assert.Zero(t, a.total)
+	})
+
+	t.Run("too many messages", func(t *testing.T) {
+		a := newChunkAssembler(10)
+		for id := range int64(maxChunkedMessages) {
+			_, _, _, err := a.add(chunk(id, "", false))
+			require.NoError(t, err)
+		}
+		_, _, _, err := a.add(chunk(maxChunkedMessages, "", false))
+		assert.ErrorIs(t, err, errTooManyChunked)
+	})
+
+	t.Run("skipped frames", func(t *testing.T) {
+		a := newChunkAssembler(100)
+		encoded := base64.StdEncoding.EncodeToString([]byte(`{"type":1,"id":7,"args":["xxxxxxxx`))
+		first := []byte(`{"type":4,"id":3,"data":"` + encoded + `"}`)
+		oversized, complete, err := a.skip(3, &oversizedMessage{size: 500, prefix: first, suffix: first[len(first)-oversizedSuffix:]})
+		require.NoError(t, err)
+		assert.False(t, complete)
+		assert.Nil(t, oversized)
+
+		// chunks of a skipped message are not appended to it
+		_, oversized, complete, err = a.add(chunk(3, `xxx"]`, false))
+		require.NoError(t, err)
+		assert.False(t, complete)
+		assert.Nil(t, oversized)
+
+		last := []byte(`{"type":4,"id":3,"data":"eHh4","last":true}`)
+		oversized, complete, err = a.skip(3, &oversizedMessage{size: 500, prefix: last, suffix: last[len(last)-oversizedSuffix:]})
+		require.NoError(t, err)
+		assert.True(t, complete)
+		require.NotNil(t, oversized)
+		assert.Equal(t, 1005, oversized.size)
+		typ, id, ok := messageHeader(oversized.prefix)
+		assert.True(t, ok)
+		assert.Equal(t, MsgCall, typ)
+		assert.Equal(t, int64(7), id)
+		assert.Empty(t, a.messages)
+	})
+}
+
+func TestChunkedCalls(t *testing.T) {
+	big := strings.Repeat("x", 10000)
+
+	t.Run("echo", func(t *testing.T) {
+		a, _ := newTestPair(t, &Options{FrameSize: 256}, &Options{FrameSize: 256}, nil, []any{&echoEndpoint{}})
+
+		res, err := a.Call("echoEndpoint.Echo", big)
+		require.NoError(t, err)
+		assert.Equal(t, big, res[0])
+	})
+
+	t.Run("frame over limit", func(t *testing.T) {
+		a, _ := newTestPair(t, &Options{FrameSize: 4096}, &Options{MaxMessageSize: 1000}, nil, []any{&echoEndpoint{}})
+
+		_, err := a.Call("echoEndpoint.Echo", big)
+		assert.ErrorIs(t, err, ErrMessageTooLarge)
+
+		res, err := a.Call("echoEndpoint.Echo", "small")
+		require.NoError(t, err)
+		assert.Equal(t, "small", res[0])
+	})
+
+	t.Run("reassembled message over limit", func(t *testing.T) {
+		a, _ := newTestPair(t, &Options{FrameSize: 256}, &Options{MaxMessageSize: 5000}, nil, []any{&echoEndpoint{}})
+
+		_, err := a.Call("echoEndpoint.Echo", big)
+		assert.ErrorIs(t, err, ErrMessageTooLarge)
+
+		res, err := a.Call("echoEndpoint.Echo", "small")
+		require.NoError(t, err)
+		assert.Equal(t, "small", res[0])
+	})
+}
+
+func TestMethodLanes(t *testing.T) {
+	metrics := NewMemoryMetrics()
+	lanes := map[string]Lane{"echoEndpoint.Echo": LaneControl}
+	a, _ := newTestPair(t, &Options{MethodLanes: lanes, Metrics: metrics}, &Options{MethodLanes: lanes}, nil, []any{&echoEndpoint{}})
+
+	res, err := a.Call("echoEndpoint.Echo", "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "hi", res[0])
+
+	h := metrics.Histogram(MetricWriteQueueDelay, Labels{Lane: "control"})
+	require.NotNil(t, h)
+	assert.Equal(t, uint64(1), h.Count)
+	assert.Nil(t, metrics.Histogram(MetricWriteQueueDelay, Labels{Lane: "normal"}))
+	assert.Equal(t, float64(0), metrics.Gauge(MetricWriteQueueMessages, Labels{Lane: "control"}))
+}
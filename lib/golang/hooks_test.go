@@ -0,0 +1,94 @@
+package golang
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks(t *testing.T) {
+	t.Run("lifecycle", func(t *testing.T) {
+		var mu sync.Mutex
+		var events []string
+		var exit *ChildExitError
+		disconnected := make(chan struct{})
+		event := func(name string) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, name)
+		}
+
+		launcher := &inProcessLauncher{}
+		p, err := NewParentWithLauncher(context.Background(), launcher, &Options{
+			SocketHandoff: SocketHandoffEnv,
+			OnConnected:   func() { event("connected") },
+			OnDisconnected: func(err error) {
+				assert.NoError(t, err)
+				event("disconnected")
+				close(disconnected)
+			},
+			OnError: func(err *InternalError) { event("error") },
+			OnChildExit: func(e *ChildExitError) {
+				event("exit")
+				exit = e
+			},
+		})
+		require.NoError(t, err)
+		require.NoError(t, p.Start())
+		require.NoError(t, p.Stop())
+		select {
+		case <-disconnected:
+		case <-time.After(time.Second):
+			t.Fatal("disconnect not reported")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, events)
+		assert.Equal(t, "connected", events[0])
+		assert.ElementsMatch(t, []string{"connected", "exit", "disconnected"}, events)
+		require.NotNil(t, exit)
+		assert.True(t, exit.Requested)
+	})
+
+	t.Run("every error reported", func(t *testing.T) {
+		errs := make(chan *InternalError, 2)
+		_, b := newTestPair(t, &Options{OnError: func(err *InternalError) { errs <- err }}, nil, nil, nil)
+
+		for id := range int64(2) {
+			_, err := b.sendMsg(context.Background(), Message{Type: MsgResponse, Id: 100 + id}, LaneNormal)
+			require.NoError(t, err)
+		}
+		for range 2 {
+			select {
+			case err := <-errs:
+				assert.ErrorContains(t, err, "unknown call id")
+				assert.Equal(t, SeverityWarning, err.Severity)
+			case <-time.After(time.Second):
+				t.Fatal("error not reported")
+			}
+		}
+	})
+
+	t.Run("disconnected with error", func(t *testing.T) {
+		disconnected := make(chan error, 1)
+		_, b := newTestPair(t, &Options{OnDisconnected: func(err error) { disconnected <- err }}, nil, nil, nil)
+
+		data, err := json.Marshal("garbage")
+		require.NoError(t, err)
+		_, err = b.conn.Write(append(data, '\n'))
+		require.NoError(t, err)
+
+		select {
+		case err := <-disconnected:
+			assert.ErrorContains(t, err, "unmarshal message")
+		case <-time.After(time.Second):
+			t.Fatal("disconnect not reported")
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package golang
+
+import "context"
+
+// Invoker performs an outgoing call. It is the final link of the client interceptor chain.
+type Invoker func(ctx context.Context, method string, args Vals) (Vals, error)
+
+// Handler executes an incoming call on a local API. It is the final link of the server interceptor chain.
+type Handler func(ctx context.Context, method string, args Vals) (Vals, error)
+
+// ClientInterceptor wraps every outgoing Call. It may inspect or modify method and args,
+// inspect the result, or return without calling invoker to short-circuit the call.
+type ClientInterceptor func(ctx context.Context, method string, args Vals, invoker Invoker) (Vals, error)
+
+// ServerInterceptor wraps every incoming call. It may inspect or modify method and args,
+// inspect the result, or return without calling handler to short-circuit the call.
+type ServerInterceptor func(ctx context.Context, method string, args Vals, handler Handler) (Vals, error)
+
+// chainClientInterceptors composes interceptors so that the first one is the outermost.
+func chainClientInterceptors(interceptors []ClientInterceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, method string, args Vals) (Vals, error) {
+			return interceptor(ctx, method, args, next)
+		}
+	}
+	return invoker
+}
+
+// chainServerInterceptors composes interceptors so that the first one is the outermost.
+func chainServerInterceptors(interceptors []ServerInterceptor, handler Handler) Handler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, method string, args Vals) (Vals, error) {
+			return interceptor(ctx, method, args, next)
+		}
+	}
+	return handler
+}
@@ -0,0 +1,82 @@
+package golang
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterceptors(t *testing.T) {
+	t.Run("chain order", func(t *testing.T) {
+		var trace []string
+		record := func(name string) ClientInterceptor {
+			return func(ctx context.Context, method string, args Vals, invoker Invoker) (Vals, error) {
+				trace = append(trace, name+" before")
+				res, err := invoker(ctx, method, args)
+				trace = append(trace, name+" after")
+				return res, err
+			}
+		}
+		invoker := chainClientInterceptors([]ClientInterceptor{record("first"), record("second")},
+			func(ctx context.Context, method string, args Vals) (Vals, error) {
+				trace = append(trace, "invoke")
+				return nil, nil
+			})
+		_, err := invoker(context.Background(), "A.B", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"first before", "second before", "invoke", "second after", "first after"}, trace)
+	})
+
+	t.Run("client and server see method, args and result", func(t *testing.T) {
+		var clientSaw, serverSaw string
+		client := &Options{ClientInterceptors: []ClientInterceptor{
+			func(ctx context.Context, method string, args Vals, invoker Invoker) (Vals, error) {
+				res, err := invoker(ctx, method, args)
+				clientSaw = fmt.Sprintf("%s %v %v %v", method, args, res, err)
+				return res, err
+			},
+		}}
+		server := &Options{ServerInterceptors: []ServerInterceptor{
+			func(ctx context.Context, method string, args Vals, handler Handler) (Vals, error) {
+				res, err := handler(ctx, method, args)
+				serverSaw = fmt.Sprintf("%s %v %v %v", method, args, res, err)
+				return res, err
+			},
+		}}
+		a, _ := newTestPair(t, client, server, nil, []any{&testEndpoint{}})
+
+		res, err := a.Call("testEndpoint.Hello", "kitten")
+		require.NoError(t, err)
+		assert.Equal(t, Vals{"hello kitten"}, res)
+		assert.Equal(t, "testEndpoint.Hello [kitten] [hello kitten] <nil>", clientSaw)
+		assert.Equal(t, "testEndpoint.Hello [kitten] [hello kitten] <nil>", serverSaw)
+	})
+
+	t.Run("client short-circuit", func(t *testing.T) {
+		client := &Options{ClientInterceptors: []ClientInterceptor{
+			func(ctx context.Context, method string, args Vals, invoker Invoker) (Vals, error) {
+				return Vals{"cached"}, nil
+			},
+		}}
+		a := newIpcCommon(context.Background(), client, nil)
+
+		res, err := a.Call("testEndpoint.Hello", "kitten")
+		require.NoError(t, err)
+		assert.Equal(t, Vals{"cached"}, res)
+	})
+
+	t.Run("server short-circuit", func(t *testing.T) {
+		server := &Options{ServerInterceptors: []ServerInterceptor{
+			func(ctx context.Context, method string, args Vals, handler Handler) (Vals, error) {
+				return nil, fmt.Errorf("permission denied")
+			},
+		}}
+		a, _ := newTestPair(t, nil, server, nil, []any{&testEndpoint{}})
+
+		_, err := a.Call("testEndpoint.Hello", "kitten")
+		assert.ErrorContains(t, err, "permission denied")
+	})
+}
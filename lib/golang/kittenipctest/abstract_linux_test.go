@@ -0,0 +1,19 @@
+package kittenipctest
+
+import (
+	"testing"
+
+	kittenipc "github.com/egor3f/kitten-ipc/lib/golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbstractSocketChild(t *testing.T) {
+	child := StartChild(t, "storage", &kittenipc.Options{AbstractSocket: true})
+
+	api := &StorageApi{Ipc: child}
+	r, err := api.Sqrt(9)
+	require.NoError(t, err)
+	assert.Equal(t, 3, r)
+	assert.NoError(t, child.Stop())
+}
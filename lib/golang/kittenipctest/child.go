@@ -0,0 +1,157 @@
+package kittenipctest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	kittenipc "github.com/egor3f/kitten-ipc/lib/golang"
+)
+
+// childEnv names the child a re-executed test binary should run.
+const childEnv = "KITTENIPC_TEST_CHILD"
+
+// ChildMain runs the test binary as a kittenipc child when it is started by StartChild, otherwise it runs the tests.
+// Call it from TestMain. children maps child names to functions returning their local APIs.
+func ChildMain(m *testing.M, children map[string]func() []any) {
+	name, ok := os.LookupEnv(childEnv)
+	if !ok {
+		os.Exit(m.Run())
+	}
+
+	newApis, ok := children[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "kittenipctest: unknown child %q\n", name)
+		os.Exit(2)
+	}
+	if err := runChild(newApis()); err != nil {
+		fmt.Fprintf(os.Stderr, "kittenipctest: child %s: %v\n", name, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func runChild(localApis []any) error {
+	ipc, err := kittenipc.NewChild(nil, localApis...)
+	if err != nil {
+		return err
+	}
+	if err := ipc.Start(); err != nil {
+		return err
+	}
+	return ipc.Wait()
+}
+
+// Child is a test binary re-executed as a kittenipc child.
+type Child struct {
+	*kittenipc.ParentIPC
+	stderr  *syncBuffer
+	waitMu  sync.Mutex
+	waited  bool
+	waitErr error
+}
+
+// StartChild re-executes the test binary as the child registered in ChildMain under name and connects to it.
+// The child is stopped when the test finishes unless it was waited for.
+func StartChild(t testing.TB, name string, opts *kittenipc.Options, localApis ...any) *Child {
+	t.Helper()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("get test executable: %v", err)
+	}
+	stderr := &syncBuffer{}
+	cmd := exec.Command(exe)
+	cmd.Env = append(os.Environ(), childEnv+"="+name)
+	cmd.Stderr = stderr
+
+	ipc, err := kittenipc.NewParent(cmd, opts, localApis...)
+	if err != nil {
+		t.Fatalf("create parent: %v", err)
+	}
+	c := &Child{ParentIPC: ipc, stderr: stderr}
+
+	if err := ipc.Start(); err != nil {
+		t.Fatalf("start child %s: %v\nstderr:\n%s", name, err, c.Stderr())
+	}
+
+	t.Cleanup(func() {
+		c.waitMu.Lock()
+		defer c.waitMu.Unlock()
+		if !c.waited {
+			c.waitErr = c.ParentIPC.Stop()
+			c.waited = true
+		}
+	})
+	return c
+}
+
+// Wait waits for the child to exit. It may be called multiple times.
+func (c *Child) Wait() error {
+	c.waitMu.Lock()
+	defer c.waitMu.Unlock()
+	if !c.waited {
+		c.waitErr = c.ParentIPC.Wait()
+		c.waited = true
+	}
+	return c.waitErr
+}
+
+// Stderr returns everything the child wrote to stderr so far.
+func (c *Child) Stderr() string {
+	return c.stderr.String()
+}
+
+// AssertExit waits for the child to exit and checks its exit code.
+func (c *Child) AssertExit(t testing.TB, code int) bool {
+	t.Helper()
+
+	err := c.Wait()
+	exitCode := 0
+	if err != nil {
+		var exitErr *kittenipc.ChildExitError
+		if !errors.As(err, &exitErr) || exitErr.Signal != 0 {
+			t.Errorf("child did not exit cleanly: %v\nstderr:\n%s", err, c.Stderr())
+			return false
+		}
+		exitCode = exitErr.ExitCode
+	}
+	if exitCode != code {
+		t.Errorf("child exit code: expected %d, got %d\nstderr:\n%s", code, exitCode, c.Stderr())
+		return false
+	}
+	return true
+}
+
+// AssertStderrContains checks that the child wrote s to stderr.
+func (c *Child) AssertStderrContains(t testing.TB, s string) bool {
+	t.Helper()
+
+	if stderr := c.Stderr(); !strings.Contains(stderr, s) {
+		t.Errorf("child stderr does not contain %q:\n%s", s, stderr)
+		return false
+	}
+	return true
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,76 @@
+package kittenipctest
+
+import (
+	"testing"
+
+	kittenipc "github.com/egor3f/kitten-ipc/lib/golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartChild(t *testing.T) {
+	t.Run("calls and stop", func(t *testing.T) {
+		child := StartChild(t, "storage", nil, &Greeter{})
+
+		api := &StorageApi{Ipc: child}
+		r, err := api.Sqrt(26)
+		require.NoError(t, err)
+		assert.Equal(t, 5, r)
+
+		assert.NoError(t, child.Stop())
+	})
+
+	t.Run("env socket handoff", func(t *testing.T) {
+		child := StartChild(t, "storage", &kittenipc.Options{SocketHandoff: kittenipc.SocketHandoffEnv})
+
+		api := &StorageApi{Ipc: child}
+		r, err := api.Sqrt(4)
+		require.NoError(t, err)
+		assert.Equal(t, 2, r)
+	})
+
+	t.Run("exit code and stderr", func(t *testing.T) {
+		child := StartChild(t, "storage", nil)
+
+		_, err := child.Call("Process.Exit", 3)
+		require.NoError(t, err)
+
+		child.AssertExit(t, 3)
+		child.AssertStderrContains(t, "exiting with code 3")
+	})
+
+	t.Run("clean exit", func(t *testing.T) {
+		child := StartChild(t, "storage", nil)
+
+		_, err := child.Call("Process.Exit", 0)
+		require.NoError(t, err)
+
+		child.AssertExit(t, 0)
+	})
+}
+
+// fakeT records failures of assertions under test.
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.failed = true
+}
+
+func TestChildAssertionsFail(t *testing.T) {
+	child := StartChild(t, "storage", nil)
+	_, err := child.Call("Process.Exit", 1)
+	require.NoError(t, err)
+
+	ft := &fakeT{TB: t}
+	assert.False(t, child.AssertExit(ft, 0))
+	assert.True(t, ft.failed)
+
+	ft = &fakeT{TB: t}
+	assert.False(t, child.AssertStderrContains(ft, "not written"))
+	assert.True(t, ft.failed)
+}
@@ -0,0 +1,43 @@
+package kittenipctest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Process lets tests make the child exit.
+type Process struct{}
+
+func (p *Process) Exit(code int) error {
+	fmt.Fprintf(os.Stderr, "exiting with code %d\n", code)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		os.Exit(code)
+	}()
+	return nil
+}
+
+// SpawnSleep starts a grandchild process and returns its pid.
+func (p *Process) SpawnSleep() (int, error) {
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	go func() { _ = cmd.Wait() }()
+	return cmd.Process.Pid, nil
+}
+
+func TestMain(m *testing.M) {
+	ChildMain(m, map[string]func() []any{
+		"storage": func() []any { return []any{&Storage{}, &Process{}} },
+		"stubborn": func() []any {
+			signal.Ignore(syscall.SIGINT)
+			return []any{&Storage{}, &Process{}}
+		},
+	})
+}
@@ -0,0 +1,28 @@
+// Package kittenipctest provides helpers for testing code that uses kittenipc.
+package kittenipctest
+
+import (
+	"net"
+	"testing"
+
+	kittenipc "github.com/egor3f/kitten-ipc/lib/golang"
+)
+
+// Pair connects two peers over an in-memory pipe, without a subprocess.
+// Messages go through the same serialization as over a socket, so generated remote APIs can use either peer as Ipc.
+// The peers are stopped when the test finishes.
+func Pair(t testing.TB, parentOpts, childOpts *kittenipc.Options, parentApis, childApis []any) (parent, child *kittenipc.Peer) {
+	t.Helper()
+
+	parentConn, childConn := net.Pipe()
+	parent = kittenipc.NewPeer(parentConn, parentOpts, parentApis...)
+	child = kittenipc.NewPeer(childConn, childOpts, childApis...)
+	parent.Start()
+	child.Start()
+
+	t.Cleanup(func() {
+		_ = parent.Stop()
+		_ = child.Stop()
+	})
+	return parent, child
+}
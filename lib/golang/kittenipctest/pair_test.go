@@ -0,0 +1,95 @@
+package kittenipctest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	kittenipc "github.com/egor3f/kitten-ipc/lib/golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errNegative = kittenipc.NewError("Negative", "negative value")
+
+type Storage struct{}
+
+func (s *Storage) Reverse(data []byte) ([]byte, error) {
+	reversed := make([]byte, len(data))
+	for i, b := range data {
+		reversed[len(data)-1-i] = b
+	}
+	return reversed, nil
+}
+
+func (s *Storage) Sqrt(ctx context.Context, n int) (int, error) {
+	if n < 0 {
+		return 0, errNegative
+	}
+	r := 0
+	for (r+1)*(r+1) <= n {
+		r++
+	}
+	return r, nil
+}
+
+type Greeter struct{}
+
+func (g *Greeter) Greet(name string) (string, error) {
+	return "hello " + name, nil
+}
+
+// StorageApi is written the way kitcom generates remote APIs.
+type StorageApi struct {
+	Ipc kittenipc.IpcCommon
+}
+
+func (s *StorageApi) Sqrt(n int) (int, error) {
+	results, err := s.Ipc.Call("Storage.Sqrt", n)
+	if err != nil {
+		return 0, fmt.Errorf("call to Storage.Sqrt failed: %w", err)
+	}
+	return int(results[0].(float64)), nil
+}
+
+func TestPair(t *testing.T) {
+	parent, child := Pair(t, nil, nil, []any{&Greeter{}}, []any{&Storage{}})
+
+	t.Run("parent calls child", func(t *testing.T) {
+		api := &StorageApi{Ipc: parent}
+		r, err := api.Sqrt(17)
+		require.NoError(t, err)
+		assert.Equal(t, 4, r)
+
+		_, err = api.Sqrt(-1)
+		assert.ErrorIs(t, err, errNegative)
+	})
+
+	t.Run("child calls parent", func(t *testing.T) {
+		results, err := child.Call("Greeter.Greet", "parent")
+		require.NoError(t, err)
+		assert.Equal(t, kittenipc.Vals{"hello parent"}, results)
+	})
+
+	t.Run("blob roundtrip", func(t *testing.T) {
+		results, err := parent.Call("Storage.Reverse", []byte{1, 2, 3})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		converted := parent.ConvType(reflect.TypeOf([]byte{}), reflect.TypeOf(results[0]), results[0])
+		assert.Equal(t, []byte{3, 2, 1}, converted)
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		_, err := parent.Call("Storage.Missing")
+		assert.ErrorIs(t, err, kittenipc.ErrMethodNotFound)
+	})
+}
+
+func TestPairStop(t *testing.T) {
+	parent, child := Pair(t, nil, nil, nil, nil)
+	require.NoError(t, parent.Stop())
+	assert.NoError(t, child.Wait())
+	_, err := child.Call("Greeter.Greet", "anyone")
+	assert.Error(t, err)
+}
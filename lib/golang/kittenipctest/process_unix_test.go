@@ -0,0 +1,66 @@
+//go:build unix
+
+package kittenipctest
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	kittenipc "github.com/egor3f/kitten-ipc/lib/golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// processGone reports whether pid has exited. Zombies count as gone, since nobody may reap them in a container.
+func processGone(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return true
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	end := strings.LastIndexByte(string(stat), ')')
+	return end >= 0 && strings.HasPrefix(string(stat[end+1:]), " Z")
+}
+
+func spawnSleep(t *testing.T, child *Child) int {
+	results, err := child.Call("Process.SpawnSleep")
+	require.NoError(t, err)
+	pid := int(results[0].(float64))
+	t.Cleanup(func() { _ = syscall.Kill(pid, syscall.SIGKILL) })
+	return pid
+}
+
+func TestProcessGroup(t *testing.T) {
+	t.Run("stop reaches grandchildren", func(t *testing.T) {
+		child := StartChild(t, "storage", nil)
+		pid := spawnSleep(t, child)
+
+		require.NoError(t, child.Stop())
+		assert.Eventually(t, func() bool { return processGone(pid) }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("shared process group", func(t *testing.T) {
+		child := StartChild(t, "storage", &kittenipc.Options{SharedProcessGroup: true, KillGracePeriod: -1})
+		pid := spawnSleep(t, child)
+
+		require.NoError(t, child.Stop())
+		time.Sleep(100 * time.Millisecond)
+		assert.False(t, processGone(pid))
+	})
+
+	t.Run("kill after grace period", func(t *testing.T) {
+		child := StartChild(t, "stubborn", &kittenipc.Options{KillGracePeriod: 100 * time.Millisecond})
+		pid := spawnSleep(t, child)
+
+		start := time.Now()
+		require.NoError(t, child.Stop())
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.Eventually(t, func() bool { return processGone(pid) }, 5*time.Second, 10*time.Millisecond)
+	})
+}
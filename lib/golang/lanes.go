@@ -0,0 +1,58 @@
+package golang
+
+import (
+	"fmt"
+	"time"
+)
+
+// Lane is the priority of outgoing messages. Frames of a lane are written only when no frames of higher lanes are waiting,
+// messages within a lane take turns.
+type Lane int
+
+const (
+	// LaneNormal is the default lane of calls and responses.
+	LaneNormal Lane = iota
+	// LaneControl is for small latency-sensitive calls, like health checks and cancellations.
+	LaneControl
+	// LaneBulk is for big transfers which may wait for everything else.
+	LaneBulk
+
+	laneCount = 3
+)
+
+// lanePriority lists lanes from the highest priority.
+var lanePriority = [laneCount]Lane{LaneControl, LaneNormal, LaneBulk}
+
+func (l Lane) String() string {
+	switch l {
+	case LaneNormal:
+		return "normal"
+	case LaneControl:
+		return "control"
+	case LaneBulk:
+		return "bulk"
+	default:
+		return fmt.Sprintf("Lane(%d)", int(l))
+	}
+}
+
+// methodLane returns the lane of calls to the method and their responses.
+func (ipc *ipcCommon) methodLane(method string) Lane {
+	lane, ok := ipc.methodLanes[method]
+	if !ok || lane < 0 || lane >= laneCount {
+		return LaneNormal
+	}
+	return lane
+}
+
+func (ipc *ipcCommon) observeQueueDelay(lane Lane, delay time.Duration) {
+	if ipc.metrics != nil {
+		ipc.metrics.ObserveHistogram(MetricWriteQueueDelay, Labels{Lane: lane.String()}, delay.Seconds())
+	}
+}
+
+func (ipc *ipcCommon) queueLengthChanged(lane Lane, delta int) {
+	if ipc.metrics != nil {
+		ipc.metrics.AddGauge(MetricWriteQueueMessages, Labels{Lane: lane.String()}, float64(delta))
+	}
+}
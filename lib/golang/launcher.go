@@ -0,0 +1,55 @@
+package golang
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// LaunchSpec is what the child needs to find and authenticate to the parent.
+type LaunchSpec struct {
+	// Args are appended to the arguments of the child.
+	Args []string
+	// Env is added to the environment of the child, in KEY=VALUE form.
+	Env []string
+}
+
+// Launcher starts and controls the child of ParentIPC. Launchers running the child through a wrapper,
+// like bubblewrap or a container runtime, must pass Args and Env of the spec on to the child.
+type Launcher interface {
+	// Start starts the child without waiting for it.
+	Start(spec LaunchSpec) error
+	// Wait waits for the child to exit. A failed child is reported with *exec.ExitError, like exec.Cmd does.
+	Wait() error
+	// Signal sends sig to the child. ParentIPC sends SIGINT to stop the child and SIGKILL to kill it.
+	Signal(sig syscall.Signal) error
+	// Pid returns the pid of the started child. It is what Options.PeerCheck compares the peer with.
+	Pid() int
+}
+
+// ExecLauncher runs the child with exec.Cmd. It is the launcher of NewParent.
+// Process group, output and limits options only work with it.
+type ExecLauncher struct {
+	Cmd *exec.Cmd
+	// ProcessGroup makes Signal signal the process group of the child, which must be started in its own group.
+	ProcessGroup bool
+}
+
+func (l *ExecLauncher) Start(spec LaunchSpec) error {
+	l.Cmd.Args = append(l.Cmd.Args, spec.Args...)
+	if len(spec.Env) > 0 {
+		if l.Cmd.Env == nil {
+			l.Cmd.Env = os.Environ()
+		}
+		l.Cmd.Env = append(l.Cmd.Env, spec.Env...)
+	}
+	return l.Cmd.Start()
+}
+
+func (l *ExecLauncher) Wait() error {
+	return l.Cmd.Wait()
+}
+
+func (l *ExecLauncher) Pid() int {
+	return l.Cmd.Process.Pid
+}
@@ -0,0 +1,108 @@
+package golang
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inProcessLauncher runs the child as a Peer inside the test process.
+type inProcessLauncher struct {
+	apis []any
+	opts *Options
+	peer *Peer
+	// ignoreInterrupt keeps the child running on SIGINT
+	ignoreInterrupt bool
+
+	mu      sync.Mutex
+	signals []syscall.Signal
+}
+
+func (l *inProcessLauncher) Start(spec LaunchSpec) error {
+	env := map[string]string{}
+	for _, kv := range spec.Env {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
+	}
+	conn, err := net.Dial("unix", env[ipcSocketEnv])
+	if err != nil {
+		return err
+	}
+	if err := sendHandshake(conn, env[ipcTokenEnv]); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	l.peer = NewPeer(conn, l.opts, l.apis...)
+	l.peer.Start()
+	return nil
+}
+
+func (l *inProcessLauncher) Wait() error {
+	return l.peer.Wait()
+}
+
+func (l *inProcessLauncher) Signal(sig syscall.Signal) error {
+	l.mu.Lock()
+	l.signals = append(l.signals, sig)
+	l.mu.Unlock()
+	if sig == syscall.SIGINT && l.ignoreInterrupt {
+		return nil
+	}
+	go func() { _ = l.peer.Stop() }()
+	return nil
+}
+
+func (l *inProcessLauncher) Pid() int {
+	return os.Getpid()
+}
+
+func TestLauncher(t *testing.T) {
+	t.Run("in process child", func(t *testing.T) {
+		launcher := &inProcessLauncher{apis: []any{&testEndpoint{}}}
+		p, err := NewParentWithLauncher(context.Background(), launcher, &Options{SocketHandoff: SocketHandoffEnv})
+		require.NoError(t, err)
+		require.NoError(t, p.Start())
+
+		res, err := p.Call("testEndpoint.Hello", "kitten")
+		require.NoError(t, err)
+		assert.Equal(t, "hello kitten", res[0])
+
+		require.NoError(t, p.Stop())
+		assert.Equal(t, []syscall.Signal{syscall.SIGINT}, launcher.signals)
+	})
+
+	t.Run("interrupted once on wait timeout", func(t *testing.T) {
+		launcher := &inProcessLauncher{ignoreInterrupt: true}
+		p, err := NewParentWithLauncher(context.Background(), launcher, &Options{
+			SocketHandoff:   SocketHandoffEnv,
+			KillGracePeriod: 200 * time.Millisecond,
+		})
+		require.NoError(t, err)
+		require.NoError(t, p.Start())
+
+		_ = p.Wait(10 * time.Millisecond)
+		launcher.mu.Lock()
+		defer launcher.mu.Unlock()
+		assert.Equal(t, []syscall.Signal{syscall.SIGINT, syscall.SIGKILL}, launcher.signals)
+	})
+
+	t.Run("socket in args", func(t *testing.T) {
+		p, err := NewParentWithLauncher(context.Background(), &inProcessLauncher{}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{ipcSocketArg, p.socketPath}, p.spec.Args)
+		assert.Equal(t, []string{ipcTokenEnv + "=" + p.token}, p.spec.Env)
+	})
+
+	t.Run("process options need exec launcher", func(t *testing.T) {
+		_, err := NewParentWithLauncher(context.Background(), &inProcessLauncher{}, &Options{LogChildOutput: true})
+		assert.Error(t, err)
+	})
+}
@@ -15,6 +15,14 @@ func TestNewParent(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("env socket handoff", func(t *testing.T) {
+		cmd := exec.Command("/bin/sh", "-c", "exec node child.js", ipcSocketArg)
+		p, err := NewParent(cmd, &Options{SocketHandoff: SocketHandoffEnv})
+		assert.NoError(t, err)
+		assert.Empty(t, p.spec.Args)
+		assert.Contains(t, p.spec.Env, ipcSocketEnv+"="+p.socketPath)
+	})
+
 	t.Run("nonexistent binary", func(t *testing.T) {
 		cmd := exec.Command("/nonexistent/binary")
 		p, err := NewParent(cmd, nil)
@@ -0,0 +1,56 @@
+package golang
+
+import (
+	"fmt"
+	"time"
+)
+
+// ResourceLimits restrict the child process. Zero values mean no limit. Linux only.
+//
+// Rlimits are set before the command runs: the child starts as /bin/sh, which sets them with ulimit and executes
+// the command in the same process. The command gets its resolved path as argv[0], and AddressSpace is rounded up
+// to KiB. Cgroup limits apply from the start too.
+type ResourceLimits struct {
+	// AddressSpace is RLIMIT_AS in bytes.
+	AddressSpace uint64
+	// OpenFiles is RLIMIT_NOFILE.
+	OpenFiles uint64
+	// CPUTime is RLIMIT_CPU, rounded up to seconds. The child gets SIGXCPU when it is exceeded.
+	CPUTime time.Duration
+
+	// Cgroup is a writable cgroup v2 directory. The child is placed into a new cgroup created inside it,
+	// which is removed with all remaining processes after the child exits.
+	Cgroup string
+	// MemoryMax is memory.max of the child cgroup in bytes.
+	MemoryMax uint64
+	// CPUMax is cpu.max of the child cgroup in CPUs, e.g. 0.5 for half of a CPU.
+	CPUMax float64
+}
+
+// MemoryLimitError is returned when the child is killed by the OOM killer of its cgroup.
+type MemoryLimitError struct {
+	Limit uint64
+	Err   error
+}
+
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("child exceeded memory limit of %d bytes: %v", e.Limit, e.Err)
+}
+
+func (e *MemoryLimitError) Unwrap() error {
+	return e.Err
+}
+
+// CPULimitError is returned when the child is killed for exceeding its CPU time limit.
+type CPULimitError struct {
+	Limit time.Duration
+	Err   error
+}
+
+func (e *CPULimitError) Error() string {
+	return fmt.Sprintf("child exceeded CPU time limit of %s: %v", e.Limit, e.Err)
+}
+
+func (e *CPULimitError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,208 @@
+package golang
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const cgroupCPUPeriod = 100000 // microseconds
+
+func checkLimits(limits *ResourceLimits) error {
+	switch {
+	case limits == nil:
+		return nil
+	case limits.CPUTime < 0:
+		return fmt.Errorf("negative CPUTime limit: %s", limits.CPUTime)
+	case limits.CPUMax < 0 || math.IsNaN(limits.CPUMax):
+		return fmt.Errorf("invalid CPUMax limit: %v", limits.CPUMax)
+	case limits.Cgroup == "" && (limits.MemoryMax > 0 || limits.CPUMax > 0):
+		return fmt.Errorf("MemoryMax and CPUMax limits require Cgroup")
+	}
+	return nil
+}
+
+// prepareLimits wraps the command to set rlimits and creates the cgroup of the child, so it is placed there by clone.
+func (p *ParentIPC) prepareLimits() error {
+	if p.limits == nil {
+		return nil
+	}
+	if err := p.prepareRlimits(); err != nil {
+		return err
+	}
+	if p.limits.Cgroup == "" {
+		return nil
+	}
+
+	dir := filepath.Join(p.limits.Cgroup, fmt.Sprintf("kitten-ipc-%d-%s", os.Getpid(), rand.Text()))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return fmt.Errorf("create cgroup: %w", err)
+	}
+	fail := func(err error) error {
+		_ = os.Remove(dir)
+		return err
+	}
+
+	if p.limits.MemoryMax > 0 {
+		if err := writeCgroupFile(dir, "memory.max", strconv.FormatUint(p.limits.MemoryMax, 10)); err != nil {
+			return fail(err)
+		}
+	}
+	if p.limits.CPUMax > 0 {
+		quota := int64(math.Ceil(p.limits.CPUMax * cgroupCPUPeriod))
+		if err := writeCgroupFile(dir, "cpu.max", fmt.Sprintf("%d %d", quota, cgroupCPUPeriod)); err != nil {
+			return fail(err)
+		}
+	}
+
+	f, err := os.Open(dir)
+	if err != nil {
+		return fail(fmt.Errorf("open cgroup: %w", err))
+	}
+	if p.cmd.SysProcAttr == nil {
+		p.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	p.cmd.SysProcAttr.UseCgroupFD = true
+	p.cmd.SysProcAttr.CgroupFD = int(f.Fd())
+	p.cgroupDir = dir
+	p.cgroupFile = f
+	return nil
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0); err != nil {
+		return fmt.Errorf("set %s (is the controller enabled in cgroup.subtree_control of the parent cgroup?): %w", name, err)
+	}
+	return nil
+}
+
+// rlimitShell runs the command with rlimits set by the ulimit builtin, see prepareRlimits.
+const rlimitShell = "/bin/sh"
+
+type rlimit struct {
+	flag     byte // option of ulimit
+	cur, max uint64
+}
+
+// rlimits lists rlimits to set in the child.
+func (limits *ResourceLimits) rlimits() []rlimit {
+	var rlimits []rlimit
+	if limits.AddressSpace > 0 {
+		kib := (limits.AddressSpace + 1023) / 1024
+		rlimits = append(rlimits, rlimit{'v', kib, kib})
+	}
+	if limits.OpenFiles > 0 {
+		rlimits = append(rlimits, rlimit{'n', limits.OpenFiles, limits.OpenFiles})
+	}
+	if limits.CPUTime > 0 {
+		// SIGXCPU at the soft limit, SIGKILL a second later if it is ignored
+		seconds := uint64(math.Ceil(limits.CPUTime.Seconds()))
+		rlimits = append(rlimits, rlimit{'t', seconds, seconds + 1})
+	}
+	return rlimits
+}
+
+// prepareRlimits makes the child start as a shell which sets rlimits and executes the command in the same process,
+// so rlimits apply before the command runs any code.
+func (p *ParentIPC) prepareRlimits() error {
+	rlimits := p.limits.rlimits()
+	if len(rlimits) == 0 {
+		return nil
+	}
+
+	var script strings.Builder
+	for _, rl := range rlimits {
+		// Both limits are set first, lowering the soft limit alone cannot leave it above the hard one
+		fmt.Fprintf(&script, "ulimit -%c %d && ", rl.flag, rl.max)
+		if rl.cur != rl.max {
+			fmt.Fprintf(&script, "ulimit -S -%c %d && ", rl.flag, rl.cur)
+		}
+	}
+	script.WriteString(`exec "$0" "$@"`)
+
+	var args []string
+	if len(p.cmd.Args) > 1 {
+		args = p.cmd.Args[1:]
+	}
+	p.cmd.Args = append([]string{rlimitShell, "-c", script.String(), p.cmd.Path}, args...)
+	p.cmd.Path = rlimitShell
+	return nil
+}
+
+// limitExitError wraps err into a limit error if the child was killed for exceeding a limit.
+func (p *ParentIPC) limitExitError(err error) error {
+	if p.limits == nil {
+		return err
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	ws, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() {
+		return err
+	}
+
+	switch ws.Signal() {
+	case syscall.SIGXCPU:
+		if p.limits.CPUTime > 0 {
+			return &CPULimitError{Limit: p.limits.CPUTime, Err: err}
+		}
+	case syscall.SIGKILL:
+		if p.limits.MemoryMax > 0 && p.cgroupDir != "" && cgroupOOMKills(p.cgroupDir) > 0 {
+			return &MemoryLimitError{Limit: p.limits.MemoryMax, Err: err}
+		}
+		if p.limits.CPUTime > 0 {
+			if usage, ok := exitErr.SysUsage().(*syscall.Rusage); ok {
+				cpuTime := time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+				if cpuTime >= p.limits.CPUTime {
+					return &CPULimitError{Limit: p.limits.CPUTime, Err: err}
+				}
+			}
+		}
+	}
+	return err
+}
+
+func cgroupOOMKills(dir string) int {
+	data, err := os.ReadFile(filepath.Join(dir, "memory.events"))
+	if err != nil {
+		return 0
+	}
+	scn := bufio.NewScanner(bytes.NewReader(data))
+	for scn.Scan() {
+		name, value, _ := bytes.Cut(scn.Bytes(), []byte{' '})
+		if string(name) == "oom_kill" {
+			n, _ := strconv.Atoi(string(value))
+			return n
+		}
+	}
+	return 0
+}
+
+// releaseLimits kills processes left in the child cgroup and removes it.
+func (p *ParentIPC) releaseLimits() {
+	if p.cgroupDir == "" {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(p.cgroupDir, "cgroup.kill"), []byte("1"), 0)
+	// Killed processes leave the cgroup asynchronously
+	var err error
+	for range 50 {
+		if err = os.Remove(p.cgroupDir); err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	p.logger.Warn("remove cgroup", "cgroup", p.cgroupDir, "error", err)
+}
@@ -0,0 +1,115 @@
+package golang
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startFailing starts a script which exits before connecting and returns its output lines.
+func startFailing(t *testing.T, script string, limits *ResourceLimits) ([]string, error) {
+	var mu sync.Mutex
+	var lines []string
+	p, err := NewParent(exec.Command("sh", "-c", script), &Options{
+		Limits: limits,
+		OnChildOutput: func(line OutputLine) {
+			mu.Lock()
+			defer mu.Unlock()
+			lines = append(lines, line.Line)
+		},
+	})
+	require.NoError(t, err)
+	err = p.Start()
+	mu.Lock()
+	defer mu.Unlock()
+	return lines, err
+}
+
+// writableCgroup returns a cgroup v2 directory the test may create cgroups in.
+func writableCgroup(t *testing.T) string {
+	for _, dir := range []string{os.Getenv("KITTEN_IPC_TEST_CGROUP"), "/sys/fs/cgroup/unified", "/sys/fs/cgroup"} {
+		if dir == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "cgroup.procs")); err != nil {
+			continue
+		}
+		probe, err := os.MkdirTemp(dir, "kitten-ipc-probe-")
+		if err != nil {
+			continue
+		}
+		_ = os.Remove(probe)
+		return dir
+	}
+	t.Skip("no writable cgroup v2")
+	return ""
+}
+
+func TestResourceLimits(t *testing.T) {
+	t.Run("rlimits", func(t *testing.T) {
+		lines, err := startFailing(t, "grep -E 'Max (cpu time|open files|address space)' /proc/$$/limits; exit 1", &ResourceLimits{
+			AddressSpace: 1 << 30,
+			OpenFiles:    64,
+			CPUTime:      1500 * time.Millisecond,
+		})
+		var exitErr *ChildExitError
+		require.ErrorAs(t, err, &exitErr)
+
+		limits := map[string][]string{}
+		for _, line := range lines {
+			fields := strings.Fields(line)
+			require.GreaterOrEqual(t, len(fields), 5)
+			limits[strings.Join(fields[:len(fields)-3], " ")] = fields[len(fields)-3 : len(fields)-1]
+		}
+		assert.Equal(t, []string{"2", "3"}, limits["Max cpu time"])
+		assert.Equal(t, []string{"64", "64"}, limits["Max open files"])
+		assert.Equal(t, []string{"1073741824", "1073741824"}, limits["Max address space"])
+	})
+
+	t.Run("cpu limit", func(t *testing.T) {
+		_, err := startFailing(t, "while :; do :; done", &ResourceLimits{CPUTime: time.Second})
+		var cpuErr *CPULimitError
+		require.ErrorAs(t, err, &cpuErr)
+		assert.Equal(t, time.Second, cpuErr.Limit)
+		var exitErr *ChildExitError
+		assert.ErrorAs(t, err, &exitErr)
+	})
+
+	t.Run("cgroup", func(t *testing.T) {
+		cgroup := writableCgroup(t)
+		lines, err := startFailing(t, "cat /proc/self/cgroup; exit 1", &ResourceLimits{Cgroup: cgroup})
+		var exitErr *ChildExitError
+		require.ErrorAs(t, err, &exitErr)
+
+		var placed bool
+		for _, line := range lines {
+			if strings.HasPrefix(line, "0::") && strings.Contains(line, "/kitten-ipc-") {
+				placed = true
+			}
+		}
+		assert.True(t, placed, "child is not in its cgroup: %v", lines)
+
+		leftover, err := filepath.Glob(filepath.Join(cgroup, "kitten-ipc-*"))
+		require.NoError(t, err)
+		assert.Empty(t, leftover)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, limits := range []*ResourceLimits{
+			{MemoryMax: 1 << 30},
+			{CPUMax: 0.5},
+			{CPUTime: -time.Second},
+			{Cgroup: "/sys/fs/cgroup", CPUMax: -1},
+		} {
+			_, err := NewParent(exec.Command("true"), &Options{Limits: limits})
+			assert.Error(t, err, "%+v", limits)
+		}
+	})
+}
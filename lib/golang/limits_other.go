@@ -0,0 +1,25 @@
+//go:build !linux
+
+package golang
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func checkLimits(limits *ResourceLimits) error {
+	if limits != nil {
+		return fmt.Errorf("resource limits are not supported on %s", runtime.GOOS)
+	}
+	return nil
+}
+
+func (p *ParentIPC) prepareLimits() error {
+	return nil
+}
+
+func (p *ParentIPC) limitExitError(err error) error {
+	return err
+}
+
+func (p *ParentIPC) releaseLimits() {}
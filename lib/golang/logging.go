@@ -0,0 +1,87 @@
+package golang
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// LevelTrace is the level of raw message payload logs.
+const LevelTrace = slog.LevelDebug - 4
+
+const defaultLogPayloadLimit = 1024
+
+func newLogger(opts *Options) *slog.Logger {
+	if opts.Logger != nil {
+		return opts.Logger
+	}
+	if opts.DebugMessages {
+		return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: LevelTrace}))
+	}
+	return slog.New(slog.DiscardHandler)
+}
+
+// logPayload logs a message sent or received, with blobs replaced, redacted by Options.LogRedact and truncated.
+func (ipc *ipcCommon) logPayload(event string, msg Message) {
+	if !ipc.logger.Enabled(context.Background(), LevelTrace) {
+		return
+	}
+
+	msg.Args = redactBlobs(msg.Args)
+	msg.Result = redactBlobs(msg.Result)
+	if ipc.logRedact != nil {
+		msg = ipc.logRedact(msg)
+	}
+
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(msg); err != nil {
+		ipc.logger.Warn("marshal message for log", "error", err)
+		return
+	}
+	payload := truncatePayload(strings.TrimSuffix(sb.String(), "\n"), ipc.logPayloadLimit)
+
+	ipc.logger.Log(context.Background(), LevelTrace, event,
+		slog.Int64("id", msg.Id),
+		slog.String("method", msg.Method),
+		slog.String("payload", payload),
+	)
+}
+
+// truncatePayload cuts the payload to at most limit bytes, not splitting a multi-byte character.
+func truncatePayload(payload string, limit int) string {
+	if limit <= 0 || len(payload) <= limit {
+		return payload
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(payload[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s... (%d bytes truncated)", payload[:cut], len(payload)-cut)
+}
+
+func redactBlobs(vals Vals) Vals {
+	if vals == nil {
+		return nil
+	}
+	vals = slices.Clone(vals)
+	for i, val := range vals {
+		switch v := val.(type) {
+		case []byte:
+			vals[i] = fmt.Sprintf("<blob %d bytes>", len(v))
+		case map[string]any:
+			if v["t"] == "blob" {
+				if d, ok := v["d"].(string); ok {
+					vals[i] = fmt.Sprintf("<blob %d bytes base64>", len(d))
+				}
+			}
+		}
+	}
+	return vals
+}
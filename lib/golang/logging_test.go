@@ -0,0 +1,128 @@
+package golang
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type blobEndpoint struct{}
+
+func (e *blobEndpoint) Store(token string, data any) error {
+	return nil
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use by slog handlers.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) records(t *testing.T) []map[string]any {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogging(t *testing.T) {
+	var buf syncBuffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: LevelTrace}))
+	client := &Options{
+		Logger:          logger,
+		LogPayloadLimit: 95,
+		LogRedact: func(msg Message) Message {
+			if msg.Method == "blobEndpoint.Store" {
+				msg.Args[0] = "<redacted>"
+			}
+			return msg
+		},
+	}
+	a, _ := newTestPair(t, client, nil, nil, []any{&blobEndpoint{}})
+
+	_, err := a.Call("blobEndpoint.Store", "secret-token", bytes.Repeat([]byte{1}, 100))
+	require.NoError(t, err)
+
+	records := buf.records(t)
+	require.Len(t, records, 4)
+
+	assert.Equal(t, "call started", records[0]["msg"])
+	assert.Equal(t, "client", records[0]["side"])
+
+	assert.Equal(t, "message sent", records[1]["msg"])
+	assert.Equal(t, "blobEndpoint.Store", records[1]["method"])
+	payload := records[1]["payload"].(string)
+	assert.NotContains(t, payload, "secret-token")
+	assert.Contains(t, payload, `"args":["<redacted>","<blob 136 bytes base64>"]`)
+	assert.Contains(t, payload, "bytes truncated")
+
+	assert.Equal(t, "message received", records[2]["msg"])
+
+	assert.Equal(t, "call finished", records[3]["msg"])
+	assert.Equal(t, "DEBUG", records[3]["level"])
+	assert.Contains(t, records[3], "duration")
+}
+
+func TestTruncatePayload(t *testing.T) {
+	assert.Equal(t, "short", truncatePayload("short", 5))
+	assert.Equal(t, "unlimited", truncatePayload("unlimited", 0))
+	assert.Equal(t, "ab... (3 bytes truncated)", truncatePayload("abcde", 2))
+	// "é" takes two bytes, the cut moves back to its start
+	assert.Equal(t, `"a... (3 bytes truncated)`, truncatePayload(`"aé"`, 3))
+	assert.Equal(t, `"aé... (1 bytes truncated)`, truncatePayload(`"aé"`, 4))
+}
+
+func TestLoggingDisabledLevels(t *testing.T) {
+	var buf syncBuffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	a, _ := newTestPair(t, &Options{Logger: logger}, nil, nil, []any{&errorsEndpoint{}})
+
+	_, err := a.Call("errorsEndpoint.Find", 1)
+	require.Error(t, err)
+	_, err = a.Call("errorsEndpoint.Crash")
+	require.Error(t, err)
+
+	// Errors returned by the method are logged at Info, so only the crash is logged
+	require.Eventually(t, func() bool { return len(buf.records(t)) == 1 }, time.Second, time.Millisecond)
+	records := buf.records(t)
+	assert.Equal(t, "call finished", records[0]["msg"])
+	assert.Equal(t, "WARN", records[0]["level"])
+	assert.Contains(t, records[0]["error"], "panicked")
+}
+
+func TestLoggingErrorLevels(t *testing.T) {
+	var buf syncBuffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	a, _ := newTestPair(t, &Options{Logger: logger}, &Options{Logger: logger}, nil, []any{&errorsEndpoint{}})
+
+	_, err := a.Call("errorsEndpoint.Find", 1)
+	require.Error(t, err)
+	_, err = a.Call("errorsEndpoint.Nope")
+	require.Error(t, err)
+
+	require.Eventually(t, func() bool { return len(buf.records(t)) == 4 }, time.Second, time.Millisecond)
+	levels := map[string][]string{}
+	for _, record := range buf.records(t) {
+		levels[record["side"].(string)] = append(levels[record["side"].(string)], record["level"].(string))
+	}
+	assert.ElementsMatch(t, []string{"INFO", "WARN"}, levels["client"])
+	assert.ElementsMatch(t, []string{"INFO", "WARN"}, levels["server"])
+}
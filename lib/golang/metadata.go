@@ -0,0 +1,29 @@
+package golang
+
+import (
+	"context"
+	"maps"
+)
+
+// Metadata is sent alongside call arguments, e.g. request ids, auth tokens or trace ids.
+type Metadata map[string]string
+
+type metadataKey struct{}
+
+// WithMetadata returns a context carrying md merged over metadata already present in ctx.
+// Calls made with CallContext send the metadata of their context.
+func WithMetadata(ctx context.Context, md Metadata) context.Context {
+	merged := maps.Clone(MetadataFromContext(ctx))
+	if merged == nil {
+		merged = make(Metadata, len(md))
+	}
+	maps.Copy(merged, md)
+	return context.WithValue(ctx, metadataKey{}, merged)
+}
+
+// MetadataFromContext returns metadata of ctx. Incoming calls see metadata sent by the caller
+// in the context passed to interceptors and to API methods accepting context.Context as the first parameter.
+func MetadataFromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(metadataKey{}).(Metadata)
+	return md
+}
@@ -0,0 +1,68 @@
+package golang
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type metadataEndpoint struct{}
+
+func (e *metadataEndpoint) RequestId(ctx context.Context, prefix string) (string, error) {
+	return prefix + MetadataFromContext(ctx)["request-id"], nil
+}
+
+func TestWithMetadata(t *testing.T) {
+	ctx := WithMetadata(context.Background(), Metadata{"a": "1", "b": "2"})
+	ctx2 := WithMetadata(ctx, Metadata{"b": "3"})
+	assert.Equal(t, Metadata{"a": "1", "b": "2"}, MetadataFromContext(ctx))
+	assert.Equal(t, Metadata{"a": "1", "b": "3"}, MetadataFromContext(ctx2))
+	assert.Nil(t, MetadataFromContext(context.Background()))
+}
+
+func TestMetadataPropagation(t *testing.T) {
+	var interceptorSaw Metadata
+	server := &Options{ServerInterceptors: []ServerInterceptor{
+		func(ctx context.Context, method string, args Vals, handler Handler) (Vals, error) {
+			interceptorSaw = MetadataFromContext(ctx)
+			return handler(ctx, method, args)
+		},
+	}}
+	a, _ := newTestPair(t, nil, server, nil, []any{&metadataEndpoint{}})
+
+	ctx := WithMetadata(context.Background(), Metadata{"request-id": "42"})
+	res, err := a.CallContext(ctx, "metadataEndpoint.RequestId", "req-")
+	require.NoError(t, err)
+	assert.Equal(t, Vals{"req-42"}, res)
+	assert.Equal(t, Metadata{"request-id": "42"}, interceptorSaw)
+
+	res, err = a.Call("metadataEndpoint.RequestId", "req-")
+	require.NoError(t, err)
+	assert.Equal(t, Vals{"req-"}, res)
+
+	_, err = a.Call("metadataEndpoint.RequestId")
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+}
+
+type blockingEndpoint struct {
+	release chan struct{}
+}
+
+func (e *blockingEndpoint) Block() error {
+	<-e.release
+	return nil
+}
+
+func TestCallContextCancel(t *testing.T) {
+	endpoint := &blockingEndpoint{release: make(chan struct{})}
+	a, _ := newTestPair(t, nil, nil, nil, []any{endpoint})
+	t.Cleanup(func() { close(endpoint.release) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := a.CallContext(ctx, "blockingEndpoint.Block")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
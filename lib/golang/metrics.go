@@ -0,0 +1,278 @@
+package golang
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics recorded by ipc. Call metrics are labeled with endpoint and method, write queue metrics with lane.
+const (
+	MetricClientCalls         = "kittenipc_client_calls_total"
+	MetricClientErrors        = "kittenipc_client_errors_total"
+	MetricClientCallsInFlight = "kittenipc_client_calls_in_flight"
+	MetricClientCallDuration  = "kittenipc_client_call_duration_seconds"
+	MetricServerCalls         = "kittenipc_server_calls_total"
+	MetricServerErrors        = "kittenipc_server_errors_total"
+	MetricServerCallsInFlight = "kittenipc_server_calls_in_flight"
+	MetricServerCallDuration  = "kittenipc_server_call_duration_seconds"
+	MetricBytesSent           = "kittenipc_bytes_sent_total"
+	MetricBytesReceived       = "kittenipc_bytes_received_total"
+	MetricHandshakes          = "kittenipc_handshakes_total"
+	MetricHandshakeFailures   = "kittenipc_handshake_failures_total"
+	// MetricChildStarts counts children started by ParentIPC. Parents restarting a child with a shared sink
+	// count restarts with it.
+	MetricChildStarts = "kittenipc_child_starts_total"
+	// MetricPendingCalls is the number of outgoing calls waiting for a response.
+	MetricPendingCalls = "kittenipc_pending_calls"
+	// MetricWriteQueueDelay is the time outgoing messages wait before their first frame is written.
+	MetricWriteQueueDelay = "kittenipc_write_queue_delay_seconds"
+	// MetricWriteQueueMessages is the number of outgoing messages waiting for their first frame to be written.
+	MetricWriteQueueMessages = "kittenipc_write_queue_messages"
+)
+
+// DefaultBuckets are upper bounds of duration histograms, in seconds.
+var DefaultBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type Labels struct {
+	Endpoint string
+	Method   string
+	Lane     string
+}
+
+// MetricsSink receives metrics recorded by ipc. Implementations must be safe for concurrent use.
+type MetricsSink interface {
+	AddCounter(name string, labels Labels, delta float64)
+	AddGauge(name string, labels Labels, delta float64)
+	ObserveHistogram(name string, labels Labels, value float64)
+}
+
+// unknownLabel replaces endpoint and method of incoming calls to methods which do not exist,
+// so the peer cannot create arbitrary series.
+const unknownLabel = "unknown"
+
+func methodLabels(method string) Labels {
+	endpoint, methodName, _ := strings.Cut(method, ".")
+	return Labels{Endpoint: endpoint, Method: methodName}
+}
+
+// callLabels returns labels of call metrics. Incoming calls are labeled only with known methods.
+func (ipc *ipcCommon) callLabels(method string, server bool) Labels {
+	if server {
+		if _, err := ipc.findMethod(method); err != nil {
+			return Labels{Endpoint: unknownLabel, Method: unknownLabel}
+		}
+	}
+	return methodLabels(method)
+}
+
+func (ipc *ipcCommon) startCallMetrics(labels Labels, server bool) {
+	if server {
+		ipc.metrics.AddCounter(MetricServerCalls, labels, 1)
+		ipc.metrics.AddGauge(MetricServerCallsInFlight, labels, 1)
+	} else {
+		ipc.metrics.AddCounter(MetricClientCalls, labels, 1)
+		ipc.metrics.AddGauge(MetricClientCallsInFlight, labels, 1)
+	}
+}
+
+func (ipc *ipcCommon) endCallMetrics(labels Labels, server bool, start time.Time, bytesSent, bytesReceived int, err error) {
+	ipc.metrics.AddCounter(MetricBytesSent, labels, float64(bytesSent))
+	ipc.metrics.AddCounter(MetricBytesReceived, labels, float64(bytesReceived))
+	errName, inFlightName, durationName := MetricClientErrors, MetricClientCallsInFlight, MetricClientCallDuration
+	if server {
+		errName, inFlightName, durationName = MetricServerErrors, MetricServerCallsInFlight, MetricServerCallDuration
+	}
+	if err != nil {
+		ipc.metrics.AddCounter(errName, labels, 1)
+	}
+	ipc.metrics.AddGauge(inFlightName, labels, -1)
+	ipc.metrics.ObserveHistogram(durationName, labels, time.Since(start).Seconds())
+}
+
+func (ipc *ipcCommon) pendingCallsChanged(delta int) {
+	if ipc.metrics != nil && delta != 0 {
+		ipc.metrics.AddGauge(MetricPendingCalls, Labels{}, float64(delta))
+	}
+}
+
+type metricKey struct {
+	name   string
+	labels Labels
+}
+
+type Histogram struct {
+	Buckets []float64 // upper bounds
+	Counts  []uint64  // non-cumulative count per bucket, the last one is +Inf
+	Count   uint64
+	Sum     float64
+}
+
+// MemoryMetrics is an in-memory MetricsSink.
+type MemoryMetrics struct {
+	mu         sync.Mutex
+	buckets    []float64
+	counters   map[metricKey]float64
+	gauges     map[metricKey]float64
+	histograms map[metricKey]*Histogram
+}
+
+// NewMemoryMetrics creates MemoryMetrics with histogram buckets, DefaultBuckets if none are given.
+func NewMemoryMetrics(buckets ...float64) *MemoryMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &MemoryMetrics{
+		buckets:    slices.Sorted(slices.Values(buckets)),
+		counters:   make(map[metricKey]float64),
+		gauges:     make(map[metricKey]float64),
+		histograms: make(map[metricKey]*Histogram),
+	}
+}
+
+func (m *MemoryMetrics) AddCounter(name string, labels Labels, delta float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[metricKey{name, labels}] += delta
+}
+
+func (m *MemoryMetrics) AddGauge(name string, labels Labels, delta float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gauges[metricKey{name, labels}] += delta
+}
+
+func (m *MemoryMetrics) ObserveHistogram(name string, labels Labels, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := metricKey{name, labels}
+	h, ok := m.histograms[key]
+	if !ok {
+		h = &Histogram{Buckets: m.buckets, Counts: make([]uint64, len(m.buckets)+1)}
+		m.histograms[key] = h
+	}
+	idx, _ := slices.BinarySearch(h.Buckets, value)
+	h.Counts[idx]++
+	h.Count++
+	h.Sum += value
+}
+
+func (m *MemoryMetrics) Counter(name string, labels Labels) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counters[metricKey{name, labels}]
+}
+
+func (m *MemoryMetrics) Gauge(name string, labels Labels) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.gauges[metricKey{name, labels}]
+}
+
+// Histogram returns a copy of the histogram, or nil if nothing was observed.
+func (m *MemoryMetrics) Histogram(name string, labels Labels) *Histogram {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.histograms[metricKey{name, labels}]
+	if !ok {
+		return nil
+	}
+	hCopy := *h
+	hCopy.Counts = slices.Clone(h.Counts)
+	return &hCopy
+}
+
+// WritePrometheus writes metrics in Prometheus text exposition format.
+func WritePrometheus(w io.Writer, m *MemoryMetrics) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var sb strings.Builder
+	writeSimple := func(values map[metricKey]float64, typ string) {
+		keys := sortedKeys(values)
+		for i, key := range keys {
+			if i == 0 || keys[i-1].name != key.name {
+				fmt.Fprintf(&sb, "# TYPE %s %s\n", key.name, typ)
+			}
+			fmt.Fprintf(&sb, "%s%s %s\n", key.name, formatLabels(key.labels, ""), formatFloat(values[key]))
+		}
+	}
+	writeSimple(m.counters, "counter")
+	writeSimple(m.gauges, "gauge")
+
+	keys := sortedKeys(m.histograms)
+	for i, key := range keys {
+		if i == 0 || keys[i-1].name != key.name {
+			fmt.Fprintf(&sb, "# TYPE %s histogram\n", key.name)
+		}
+		h := m.histograms[key]
+		var cumulative uint64
+		for j, bound := range h.Buckets {
+			cumulative += h.Counts[j]
+			fmt.Fprintf(&sb, "%s_bucket%s %d\n", key.name, formatLabels(key.labels, formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(&sb, "%s_bucket%s %d\n", key.name, formatLabels(key.labels, "+Inf"), h.Count)
+		fmt.Fprintf(&sb, "%s_sum%s %s\n", key.name, formatLabels(key.labels, ""), formatFloat(h.Sum))
+		fmt.Fprintf(&sb, "%s_count%s %d\n", key.name, formatLabels(key.labels, ""), h.Count)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func sortedKeys[V any](m map[metricKey]V) []metricKey {
+	keys := make([]metricKey, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b metricKey) int {
+		return strings.Compare(a.sortKey(), b.sortKey())
+	})
+	return keys
+}
+
+func (k metricKey) sortKey() string {
+	return k.name + "\x00" + k.labels.Endpoint + "\x00" + k.labels.Method + "\x00" + k.labels.Lane
+}
+
+func formatLabels(labels Labels, le string) string {
+	var pairs []string
+	if labels.Endpoint != "" {
+		pairs = append(pairs, `endpoint="`+escapeLabel(labels.Endpoint)+`"`)
+	}
+	if labels.Method != "" {
+		pairs = append(pairs, `method="`+escapeLabel(labels.Method)+`"`)
+	}
+	if labels.Lane != "" {
+		pairs = append(pairs, `lane="`+escapeLabel(labels.Lane)+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelEscaper escapes label values as the Prometheus text format expects.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return fmt.Sprintf("%g", v)
+	}
+}
@@ -0,0 +1,120 @@
+package golang
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryMetrics(t *testing.T) {
+	m := NewMemoryMetrics(0.1, 1)
+	labels := Labels{Endpoint: "E", Method: "M"}
+
+	m.AddCounter(MetricClientCalls, labels, 1)
+	m.AddCounter(MetricClientCalls, labels, 2)
+	m.AddGauge(MetricClientCallsInFlight, labels, 1)
+	m.AddGauge(MetricClientCallsInFlight, labels, -1)
+	m.ObserveHistogram(MetricClientCallDuration, labels, 0.05)
+	m.ObserveHistogram(MetricClientCallDuration, labels, 0.1)
+	m.ObserveHistogram(MetricClientCallDuration, labels, 5)
+	m.AddCounter(MetricChildStarts, Labels{}, 1)
+	m.AddGauge(MetricWriteQueueMessages, Labels{Lane: "bulk"}, 2)
+
+	assert.Equal(t, float64(3), m.Counter(MetricClientCalls, labels))
+	assert.Equal(t, float64(0), m.Gauge(MetricClientCallsInFlight, labels))
+	assert.Equal(t, float64(1), m.Counter(MetricChildStarts, Labels{}))
+	assert.Equal(t, float64(2), m.Gauge(MetricWriteQueueMessages, Labels{Lane: "bulk"}))
+	h := m.Histogram(MetricClientCallDuration, labels)
+	require.NotNil(t, h)
+	assert.Equal(t, []uint64{2, 0, 1}, h.Counts)
+	assert.Equal(t, uint64(3), h.Count)
+	assert.InDelta(t, 5.15, h.Sum, 1e-9)
+	assert.Nil(t, m.Histogram(MetricServerCallDuration, labels))
+
+	var sb strings.Builder
+	require.NoError(t, WritePrometheus(&sb, m))
+	assert.Equal(t, `# TYPE kittenipc_child_starts_total counter
+kittenipc_child_starts_total 1
+# TYPE kittenipc_client_calls_total counter
+kittenipc_client_calls_total{endpoint="E",method="M"} 3
+# TYPE kittenipc_client_calls_in_flight gauge
+kittenipc_client_calls_in_flight{endpoint="E",method="M"} 0
+# TYPE kittenipc_write_queue_messages gauge
+kittenipc_write_queue_messages{lane="bulk"} 2
+# TYPE kittenipc_client_call_duration_seconds histogram
+kittenipc_client_call_duration_seconds_bucket{endpoint="E",method="M",le="0.1"} 2
+kittenipc_client_call_duration_seconds_bucket{endpoint="E",method="M",le="1"} 2
+kittenipc_client_call_duration_seconds_bucket{endpoint="E",method="M",le="+Inf"} 3
+kittenipc_client_call_duration_seconds_sum{endpoint="E",method="M"} 5.15
+kittenipc_client_call_duration_seconds_count{endpoint="E",method="M"} 3
+`, sb.String())
+}
+
+func TestCallMetrics(t *testing.T) {
+	clientMetrics := NewMemoryMetrics()
+	serverMetrics := NewMemoryMetrics()
+	a, _ := newTestPair(t, &Options{Metrics: clientMetrics}, &Options{Metrics: serverMetrics}, nil, []any{&testEndpoint{}, &errorsEndpoint{}})
+
+	_, err := a.Call("testEndpoint.Hello", "kitten")
+	require.NoError(t, err)
+	_, err = a.Call("errorsEndpoint.Find", 1)
+	require.Error(t, err)
+	_, err = a.Call("random\"Endpoint.Method1", 1)
+	require.ErrorIs(t, err, ErrMethodNotFound)
+
+	hello := Labels{Endpoint: "testEndpoint", Method: "Hello"}
+	find := Labels{Endpoint: "errorsEndpoint", Method: "Find"}
+
+	assert.Equal(t, float64(1), clientMetrics.Counter(MetricClientCalls, hello))
+	assert.Equal(t, float64(0), clientMetrics.Counter(MetricClientErrors, hello))
+	assert.Equal(t, float64(1), clientMetrics.Counter(MetricClientErrors, find))
+	assert.Equal(t, float64(0), clientMetrics.Gauge(MetricClientCallsInFlight, hello))
+	assert.Equal(t, uint64(1), clientMetrics.Histogram(MetricClientCallDuration, hello).Count)
+	assert.Positive(t, clientMetrics.Counter(MetricBytesSent, hello))
+	assert.Positive(t, clientMetrics.Counter(MetricBytesReceived, hello))
+
+	// server metrics are recorded after the response is sent
+	require.Eventually(t, func() bool {
+		return serverMetrics.Counter(MetricServerErrors, find) == 1 &&
+			serverMetrics.Gauge(MetricServerCallsInFlight, hello) == 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, float64(1), serverMetrics.Counter(MetricServerCalls, hello))
+	assert.Equal(t, clientMetrics.Counter(MetricBytesSent, hello), serverMetrics.Counter(MetricBytesReceived, hello))
+	assert.Equal(t, clientMetrics.Counter(MetricBytesReceived, hello), serverMetrics.Counter(MetricBytesSent, hello))
+	unknown := Labels{Endpoint: unknownLabel, Method: unknownLabel}
+	assert.Equal(t, float64(1), serverMetrics.Counter(MetricServerCalls, unknown))
+	assert.Equal(t, float64(0), serverMetrics.Counter(MetricServerCalls, methodLabels("random\"Endpoint.Method1")))
+	assert.Equal(t, float64(0), clientMetrics.Gauge(MetricPendingCalls, Labels{}))
+}
+
+func TestPrometheusEscaping(t *testing.T) {
+	m := NewMemoryMetrics()
+	m.AddCounter(MetricClientCalls, Labels{Endpoint: "a\\b", Method: "say \"hi\"\nthere é"}, 1)
+
+	var sb strings.Builder
+	require.NoError(t, WritePrometheus(&sb, m))
+	assert.Equal(t, `# TYPE kittenipc_client_calls_total counter
+kittenipc_client_calls_total{endpoint="a\\b",method="say \"hi\"\nthere é"} 1
+`, sb.String())
+}
+
+func TestPendingCallsMetric(t *testing.T) {
+	metrics := NewMemoryMetrics()
+	release := make(chan struct{})
+	a, _ := newTestPair(t, &Options{Metrics: metrics}, nil, nil, []any{&blockingEndpoint{release: release}})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = a.Call("blockingEndpoint.Block")
+	}()
+	require.Eventually(t, func() bool {
+		return metrics.Gauge(MetricPendingCalls, Labels{}) == 1
+	}, time.Second, time.Millisecond)
+	close(release)
+	<-done
+	assert.Equal(t, float64(0), metrics.Gauge(MetricPendingCalls, Labels{}))
+}
@@ -0,0 +1,96 @@
+package golang
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// callObservation traces, measures and logs a single call.
+type callObservation struct {
+	ipc           *ipcCommon
+	method        string
+	labels        Labels
+	server        bool
+	start         time.Time
+	span          Span
+	bytesSent     int
+	bytesReceived int
+}
+
+func (ipc *ipcCommon) startCall(ctx context.Context, method string, server bool) (context.Context, *callObservation) {
+	o := &callObservation{
+		ipc:    ipc,
+		method: method,
+		server: server,
+		start:  time.Now(),
+	}
+	if ipc.tracer != nil {
+		if server {
+			ctx, o.span = ipc.startServerSpan(ctx, method)
+		} else {
+			ctx, o.span = ipc.startClientSpan(ctx, method)
+		}
+	}
+	if ipc.metrics != nil {
+		o.labels = ipc.callLabels(method, server)
+		ipc.startCallMetrics(o.labels, server)
+	}
+	ipc.logger.LogAttrs(ctx, slog.LevelDebug, "call started", o.side(), slog.String("method", method))
+	return ctx, o
+}
+
+func (o *callObservation) end(ctx context.Context, err error) {
+	if o.span != nil {
+		if o.server {
+			endSpan(o.span, o.bytesReceived, o.bytesSent, err)
+		} else {
+			endSpan(o.span, o.bytesSent, o.bytesReceived, err)
+		}
+	}
+	if o.ipc.metrics != nil {
+		o.ipc.endCallMetrics(o.labels, o.server, o.start, o.bytesSent, o.bytesReceived, err)
+	}
+
+	attrs := []slog.Attr{
+		o.side(),
+		slog.String("method", o.method),
+		slog.Duration("duration", time.Since(o.start)),
+	}
+	level := slog.LevelDebug
+	if err != nil {
+		level = o.errorLevel(err)
+		attrs = append(attrs, slog.Any("error", err))
+		var remoteErr *RemoteError
+		var stackErr *stackError
+		if errors.As(err, &remoteErr) && remoteErr.Stack != "" {
+			attrs = append(attrs, slog.String("remote_stack", remoteErr.Stack))
+		} else if errors.As(err, &stackErr) {
+			attrs = append(attrs, slog.String("stack", stackErr.stack))
+		}
+	}
+	o.ipc.logger.LogAttrs(ctx, level, "call finished", attrs...)
+}
+
+// errorLevel is Info for errors returned by the called method, which are part of normal operation,
+// and Warn for transport, marshal and internal errors.
+func (o *callObservation) errorLevel(err error) slog.Level {
+	for _, ipcErr := range []*Error{ErrInternal, ErrMethodNotFound, ErrInvalidArgument, ErrMessageTooLarge} {
+		if errors.Is(err, ipcErr) {
+			return slog.LevelWarn
+		}
+	}
+	var remoteErr *RemoteError
+	if o.server || errors.As(err, &remoteErr) {
+		return slog.LevelInfo
+	}
+	return slog.LevelWarn
+}
+
+func (o *callObservation) side() slog.Attr {
+	if o.server {
+		return slog.String("side", "server")
+	}
+	return slog.String("side", "client")
+}
@@ -0,0 +1,165 @@
+package golang
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+const maxOutputLine = 64 * 1024
+
+// OutputLine is a line the child wrote to stdout or stderr, without the trailing newline.
+type OutputLine struct {
+	Pid    int
+	Stream string // "stdout" or "stderr"
+	Line   string
+}
+
+// setupOutput routes child output according to opts, keeping writers set on cmd by the user.
+// Output goes to os.Stdout and os.Stderr only if there are neither user writers nor line routing.
+// It reports whether output which the user did not pipe is now copied by the parent.
+func (p *ParentIPC) setupOutput(opts *Options) bool {
+	var emit func(OutputLine)
+	switch {
+	case opts.OnChildOutput != nil:
+		emit = opts.OnChildOutput
+	case opts.LogChildOutput:
+		emit = func(line OutputLine) {
+			p.logger.Info("child output", slog.Int("pid", line.Pid), slog.String("stream", line.Stream), slog.String("line", line.Line))
+		}
+	}
+
+	if opts.StderrTail > 0 {
+		p.stderrTail = &tailBuffer{size: opts.StderrTail}
+	}
+
+	piped := (emit != nil || p.stderrTail != nil) && (isFileOrNil(p.cmd.Stdout) || isFileOrNil(p.cmd.Stderr))
+	stdout := p.outputWriter(p.cmd.Stdout, os.Stdout, "stdout", emit, nil)
+	stderr := p.outputWriter(p.cmd.Stderr, os.Stderr, "stderr", emit, p.stderrTail)
+	p.cmd.Stdout, p.cmd.Stderr = stdout, stderr
+	return piped
+}
+
+// isFileOrNil tells whether the child would inherit the writer without a pipe.
+func isFileOrNil(w io.Writer) bool {
+	if w == nil {
+		return true
+	}
+	_, ok := w.(*os.File)
+	return ok
+}
+
+func (p *ParentIPC) outputWriter(userWriter, defaultWriter io.Writer, stream string, emit func(OutputLine), tail *tailBuffer) io.Writer {
+	var writers []io.Writer
+	if userWriter != nil {
+		writers = append(writers, userWriter)
+	} else if emit == nil {
+		writers = append(writers, defaultWriter)
+	}
+	if emit != nil {
+		lw := &lineWriter{launcher: p.launcher, stream: stream, emit: emit}
+		p.lineWriters = append(p.lineWriters, lw)
+		writers = append(writers, lw)
+	}
+	if tail != nil {
+		writers = append(writers, tail)
+	}
+	if len(writers) == 1 {
+		// Keeps *os.File as is, so the child inherits it without a copying goroutine
+		return writers[0]
+	}
+	return io.MultiWriter(writers...)
+}
+
+// flushOutput emits the last unterminated lines. It is called after the child exits.
+func (p *ParentIPC) flushOutput() {
+	for _, lw := range p.lineWriters {
+		lw.flush()
+	}
+}
+
+// stderrTailString returns recent stderr of the child, if it is kept.
+func (p *ParentIPC) stderrTailString() string {
+	if p.stderrTail == nil {
+		return ""
+	}
+	return p.stderrTail.String()
+}
+
+// lineWriter splits output into lines. Lines longer than maxOutputLine are split too.
+type lineWriter struct {
+	mu       sync.Mutex
+	launcher Launcher
+	stream   string
+	emit     func(OutputLine)
+	buf      []byte
+}
+
+func (w *lineWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, data...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emitLine(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	for len(w.buf) >= maxOutputLine {
+		w.emitLine(w.buf[:maxOutputLine])
+		w.buf = w.buf[maxOutputLine:]
+	}
+	w.buf = bytes.Clone(w.buf)
+	return len(data), nil
+}
+
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.emitLine(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *lineWriter) emitLine(line []byte) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	w.emit(OutputLine{Pid: w.launcher.Pid(), Stream: w.stream, Line: string(line)})
+}
+
+// tailBuffer keeps the last size bytes written to it.
+type tailBuffer struct {
+	mu      sync.Mutex
+	size    int
+	buf     []byte
+	partial bool // buf starts in the middle of a line
+}
+
+func (b *tailBuffer) Write(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = append(b.buf, data...)
+	if drop := len(b.buf) - b.size; drop > 0 {
+		b.partial = b.buf[drop-1] != '\n'
+		b.buf = append(b.buf[:0], b.buf[drop:]...)
+	}
+	return len(data), nil
+}
+
+// String returns the kept lines. A cut first line is left out, unless it is the only one.
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.partial {
+		if i := bytes.IndexByte(b.buf, '\n'); i >= 0 && i+1 < len(b.buf) {
+			return string(b.buf[i+1:])
+		}
+	}
+	return string(b.buf)
+}
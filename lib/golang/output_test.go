@@ -0,0 +1,143 @@
+package golang
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineWriter(t *testing.T) {
+	var lines []OutputLine
+	w := &lineWriter{
+		launcher: &ExecLauncher{Cmd: &exec.Cmd{Process: &os.Process{Pid: 42}}},
+		stream:   "stderr",
+		emit:     func(line OutputLine) { lines = append(lines, line) },
+	}
+
+	_, _ = w.Write([]byte("first\nsec"))
+	_, _ = w.Write([]byte("ond\r\nthird"))
+	assert.Equal(t, []OutputLine{
+		{Pid: 42, Stream: "stderr", Line: "first"},
+		{Pid: 42, Stream: "stderr", Line: "second"},
+	}, lines)
+
+	w.flush()
+	require.Len(t, lines, 3)
+	assert.Equal(t, "third", lines[2].Line)
+
+	lines = nil
+	_, _ = w.Write(bytes.Repeat([]byte("x"), maxOutputLine+10))
+	require.Len(t, lines, 1)
+	assert.Len(t, lines[0].Line, maxOutputLine)
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{size: 8}
+	_, _ = b.Write([]byte("abc"))
+	_, _ = b.Write([]byte("defgh"))
+	assert.Equal(t, "abcdefgh", b.String())
+	_, _ = b.Write([]byte("ij"))
+	assert.Equal(t, "cdefghij", b.String())
+	_, _ = b.Write([]byte("0123456789"))
+	assert.Equal(t, "23456789", b.String())
+	_, _ = b.Write([]byte("ab\ncd\n"))
+	assert.Equal(t, "cd\n", b.String())
+	_, _ = b.Write([]byte("ef\n"))
+	assert.Equal(t, "cd\nef\n", b.String())
+}
+
+func TestChildOutput(t *testing.T) {
+	script := "echo out; echo err1 >&2; echo err2 >&2; exit 3"
+
+	t.Run("lines routed, stderr attached to exit error", func(t *testing.T) {
+		var mu sync.Mutex
+		var lines []OutputLine
+		cmd := exec.Command("sh", "-c", script)
+		p, err := NewParent(cmd, &Options{StderrTail: 4096, OnChildOutput: func(line OutputLine) {
+			mu.Lock()
+			defer mu.Unlock()
+			lines = append(lines, line)
+		}})
+		require.NoError(t, err)
+
+		err = p.Start()
+		var exitErr *ChildExitError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, "err1\nerr2\n", exitErr.Stderr)
+		assert.Equal(t, 3, exitErr.ExitCode)
+		assert.False(t, exitErr.Requested)
+		assert.Positive(t, exitErr.Duration)
+		var cmdErr *exec.ExitError
+		assert.ErrorAs(t, err, &cmdErr)
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, lines, 3)
+		for _, line := range lines {
+			assert.Equal(t, cmd.Process.Pid, line.Pid)
+		}
+		assert.Contains(t, lines, OutputLine{Pid: cmd.Process.Pid, Stream: "stdout", Line: "out"})
+		assert.Contains(t, lines, OutputLine{Pid: cmd.Process.Pid, Stream: "stderr", Line: "err2"})
+	})
+
+	t.Run("user writers kept", func(t *testing.T) {
+		var stdout, stderr syncBuffer
+		cmd := exec.Command("sh", "-c", script)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		p, err := NewParent(cmd, &Options{StderrTail: 5})
+		require.NoError(t, err)
+
+		err = p.Start()
+		var exitErr *ChildExitError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, "err2\n", exitErr.Stderr)
+		assert.Equal(t, "out\n", stdout.buf.String())
+		assert.Equal(t, "err1\nerr2\n", stderr.buf.String())
+	})
+
+	t.Run("logged", func(t *testing.T) {
+		var logs syncBuffer
+		p, err := NewParent(exec.Command("sh", "-c", script), &Options{
+			Logger:         slog.New(slog.NewJSONHandler(&logs, nil)),
+			LogChildOutput: true,
+		})
+		require.NoError(t, err)
+
+		err = p.Start()
+		var exitErr *ChildExitError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Empty(t, exitErr.Stderr)
+
+		var outputLines []string
+		for _, record := range logs.records(t) {
+			if record["msg"] == "child output" {
+				outputLines = append(outputLines, record["stream"].(string)+":"+record["line"].(string))
+			}
+		}
+		assert.ElementsMatch(t, []string{"stdout:out", "stderr:err1", "stderr:err2"}, outputLines)
+		assert.False(t, strings.Contains(exitErr.Error(), "stderr:"))
+	})
+
+	t.Run("logged without logger", func(t *testing.T) {
+		_, err := NewParent(exec.Command("sh", "-c", script), &Options{LogChildOutput: true})
+		assert.ErrorContains(t, err, "requires Logger")
+	})
+
+	t.Run("inherited by default", func(t *testing.T) {
+		cmd := exec.Command("sh", "-c", script)
+		p, err := NewParent(cmd, nil)
+		require.NoError(t, err)
+		assert.Same(t, os.Stdout, cmd.Stdout)
+		assert.Same(t, os.Stderr, cmd.Stderr)
+		assert.Zero(t, cmd.WaitDelay)
+		assert.Nil(t, p.stderrTail)
+	})
+}
@@ -2,24 +2,47 @@ package golang
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
-	"math/rand"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"slices"
+	"sync"
 	"syscall"
 	"time"
 )
 
 type ParentIPC struct {
 	*ipcCommon
-	cmd      *exec.Cmd
-	listener net.Listener
-	cmdDone  chan struct{}
-	cmdErr   error
+	launcher  Launcher
+	cmd       *exec.Cmd // nil unless launcher is ExecLauncher
+	spec      LaunchSpec
+	socketDir string
+	token     string
+	peerCheck PeerCheck
+	listener  net.Listener
+	cmdDone   chan struct{}
+	startTime time.Time
+	exit      *ChildExitError
+	exitErr   error // exit with limit classification
+
+	processGroup    bool
+	killGracePeriod time.Duration
+	interruptOnce   sync.Once
+	interruptErr    error
+	limits          *ResourceLimits
+	cgroupDir       string
+	cgroupFile      *os.File
+
+	stderrTail  *tailBuffer
+	lineWriters []*lineWriter
+
+	acceptTimeout time.Duration
+	onChildExit   func(exit *ChildExitError)
 }
 
 func NewParent(cmd *exec.Cmd, opts *Options, localApis ...any) (*ParentIPC, error) {
@@ -27,28 +50,69 @@ func NewParent(cmd *exec.Cmd, opts *Options, localApis ...any) (*ParentIPC, erro
 }
 
 func NewParentWithContext(ctx context.Context, cmd *exec.Cmd, opts *Options, localApis ...any) (*ParentIPC, error) {
+	return NewParentWithLauncher(ctx, &ExecLauncher{Cmd: cmd}, opts, localApis...)
+}
+
+// NewParentWithLauncher creates ParentIPC which starts the child with launcher.
+func NewParentWithLauncher(ctx context.Context, launcher Launcher, opts *Options, localApis ...any) (*ParentIPC, error) {
 	if opts == nil {
 		opts = &Options{}
 	}
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
 	p := ParentIPC{
-		ipcCommon: &ipcCommon{
-			localApis:     mapTypeNames(localApis),
-			pendingCalls:  make(map[int64]*pendingCall),
-			errCh:         make(chan error, 1),
-			socketPath:    filepath.Join(os.TempDir(), fmt.Sprintf("kitten-ipc-%d-%d.sock", os.Getpid(), rand.Int63())),
-			ctx:           ctx,
-			debugMessages: opts.DebugMessages,
-		},
-		cmd: cmd,
+		ipcCommon:   newIpcCommon(ctx, opts, localApis),
+		launcher:    launcher,
+		token:       token,
+		peerCheck:   opts.PeerCheck,
+		onChildExit: opts.OnChildExit,
+	}
+	if opts.AbstractSocket {
+		if runtime.GOOS != "linux" {
+			return nil, fmt.Errorf("abstract socket is not supported on %s", runtime.GOOS)
+		}
+		// Anyone may connect to an abstract socket, so the peer has to be checked
+		p.peerCheck = max(p.peerCheck, PeerCheckPid)
+		p.socketPath = fmt.Sprintf("@kitten-ipc-%d-%s", os.Getpid(), rand.Text())
+	} else {
+		// The directory is created by Start and fails if it already exists, so nobody else can own it
+		p.socketDir = filepath.Join(os.TempDir(), fmt.Sprintf("kitten-ipc-%d-%s", os.Getpid(), rand.Text()))
+		p.socketPath = filepath.Join(p.socketDir, "ipc.sock")
+	}
+	if p.peerCheck != PeerCheckNone && !peerCredSupported {
+		return nil, fmt.Errorf("peer check is not supported on %s", runtime.GOOS)
 	}
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	if l, ok := launcher.(*ExecLauncher); ok {
+		if err := p.setupExec(l, opts); err != nil {
+			return nil, err
+		}
+	} else if opts.ParentDeathSignal != 0 || opts.Limits != nil || opts.OnChildOutput != nil || opts.LogChildOutput {
+		return nil, fmt.Errorf("process options are only supported by ExecLauncher, not %T", launcher)
+	}
+	p.acceptTimeout = opts.AcceptTimeout
+	if p.acceptTimeout == 0 {
+		p.acceptTimeout = defaultAcceptTimeout
+	}
+	p.killGracePeriod = opts.KillGracePeriod
+	if p.killGracePeriod == 0 {
+		p.killGracePeriod = defaultKillGracePeriod
+	}
 
-	if slices.Contains(cmd.Args, ipcSocketArg) {
-		return nil, fmt.Errorf("you should not use `%s` argument in your command", ipcSocketArg)
+	p.spec.Env = append(p.spec.Env, ipcTokenEnv+"="+p.token)
+	switch opts.SocketHandoff {
+	case SocketHandoffArgs:
+		if p.cmd != nil && slices.Contains(p.cmd.Args, ipcSocketArg) {
+			return nil, fmt.Errorf("you should not use `%s` argument in your command", ipcSocketArg)
+		}
+		p.spec.Args = append(p.spec.Args, ipcSocketArg, p.socketPath)
+	case SocketHandoffEnv:
+		p.spec.Env = append(p.spec.Env, ipcSocketEnv+"="+p.socketPath)
+	default:
+		return nil, fmt.Errorf("unknown socket handoff: %d", opts.SocketHandoff)
 	}
-	cmd.Args = append(cmd.Args, ipcSocketArg, p.socketPath)
 
 	p.errCh = make(chan error, 1)
 	p.cmdDone = make(chan struct{})
@@ -56,58 +120,189 @@ func NewParentWithContext(ctx context.Context, cmd *exec.Cmd, opts *Options, loc
 	return &p, nil
 }
 
+// setupExec applies process, limits and output options to the command of l.
+func (p *ParentIPC) setupExec(l *ExecLauncher, opts *Options) error {
+	p.cmd = l.Cmd
+	processGroup, err := setupProcess(l.Cmd, opts)
+	if err != nil {
+		return err
+	}
+	l.ProcessGroup = processGroup
+	p.processGroup = processGroup
+	if err := checkLimits(opts.Limits); err != nil {
+		return err
+	}
+	p.limits = opts.Limits
+
+	if opts.LogChildOutput && opts.OnChildOutput == nil && opts.Logger == nil && !opts.DebugMessages {
+		return fmt.Errorf("LogChildOutput requires Logger")
+	}
+	if p.setupOutput(opts) && l.Cmd.WaitDelay == 0 {
+		// Grandchildren may keep output pipes we added open after the child exits
+		l.Cmd.WaitDelay = childWaitDelay
+	}
+	return nil
+}
+
 func (p *ParentIPC) Start() error {
-	_ = os.Remove(p.socketPath)
+	if p.socketDir != "" {
+		if err := os.Mkdir(p.socketDir, 0o700); err != nil {
+			return fmt.Errorf("create socket dir: %w", err)
+		}
+	}
 	listener, err := net.Listen("unix", p.socketPath)
 	if err != nil {
+		p.removeSocketDir()
 		return fmt.Errorf("listen unix socket: %w", err)
 	}
 	p.listener = listener
 	defer p.listener.Close()
+	if p.socketDir != "" {
+		if err := os.Chmod(p.socketPath, 0o600); err != nil {
+			p.removeSocketDir()
+			return fmt.Errorf("chmod unix socket: %w", err)
+		}
+	}
 
-	err = p.cmd.Start()
+	if err := p.prepareLimits(); err != nil {
+		p.removeSocketDir()
+		return err
+	}
+
+	p.startTime = time.Now()
+	err = p.launcher.Start(p.spec)
+	if p.cgroupFile != nil {
+		// The child is placed into the cgroup by clone, the descriptor is not needed anymore
+		_ = p.cgroupFile.Close()
+	}
 	if err != nil {
+		p.releaseLimits()
+		p.removeSocketDir()
 		return fmt.Errorf("cmd start: %w", err)
 	}
 
+	p.logger.Info("child started", "pid", p.launcher.Pid())
+	if p.metrics != nil {
+		p.metrics.AddCounter(MetricChildStarts, Labels{}, 1)
+	}
+
 	go func() {
-		p.cmdErr = p.cmd.Wait()
+		err := p.launcher.Wait()
+		p.flushOutput()
+		p.exit = newChildExitError(err, p.stopRequested.Load(), time.Since(p.startTime), p.stderrTailString())
+		p.exitErr = p.limitExitError(p.exit)
+		p.releaseLimits()
+		p.logger.Info("child exited", "pid", p.launcher.Pid(), "error", err)
+		if p.onChildExit != nil {
+			p.onChildExit(p.exit)
+		}
 		close(p.cmdDone)
 	}()
 
-	return p.acceptConn()
+	if err := p.acceptConn(); err != nil {
+		p.removeSocketDir()
+		return err
+	}
+	return nil
 }
 
-type connResult struct {
-	conn net.Conn
-	err  error
-}
+// maxPendingHandshakes limits connections authenticating at once. Further connections are closed right away,
+// so peers which connect and stay silent cannot pile up until the accept timeout.
+const maxPendingHandshakes = 8
+
+var errTooManyHandshakes = fmt.Errorf("more than %d connections authenticating", maxPendingHandshakes)
 
+// acceptConn waits for the child to connect and authenticate. Connections failing authentication
+// are closed while waiting goes on, so they cannot take the place of the child.
+// If the child never connects, the error wraps the reason of the last rejection.
 func (p *ParentIPC) acceptConn() error {
-	res := make(chan connResult, 1)
+	acceptTimeout := p.acceptTimeout
+	accepted := make(chan net.Conn)
+	acceptErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	handshakes := make(chan struct{}, maxPendingHandshakes)
+
+	var rejectMu sync.Mutex
+	var rejectErr error
+	reject := func(conn net.Conn, err error) {
+		p.logger.Warn("connection rejected", "error", err)
+		if p.metrics != nil {
+			p.metrics.AddCounter(MetricHandshakeFailures, Labels{}, 1)
+		}
+		rejectMu.Lock()
+		rejectErr = err
+		rejectMu.Unlock()
+		_ = conn.Close()
+	}
+	withRejectErr := func(err error) error {
+		rejectMu.Lock()
+		defer rejectMu.Unlock()
+		if rejectErr != nil {
+			return fmt.Errorf("%w, last rejected connection: %w", err, rejectErr)
+		}
+		return err
+	}
+
 	go func() {
-		conn, err := p.listener.Accept()
-		res <- connResult{conn: conn, err: err}
-		close(res)
+		for {
+			conn, err := p.listener.Accept()
+			if err != nil {
+				acceptErr <- err
+				return
+			}
+			select {
+			case handshakes <- struct{}{}:
+			default:
+				reject(conn, errTooManyHandshakes)
+				continue
+			}
+			go func() {
+				err := p.checkConn(conn, acceptTimeout)
+				<-handshakes
+				if err != nil {
+					reject(conn, err)
+					return
+				}
+				select {
+				case accepted <- conn:
+				case <-done:
+					_ = conn.Close()
+				}
+			}()
+		}
 	}()
 
 	select {
-	case <-time.After(time.Duration(defaultAcceptTimeout) * time.Second):
-		_ = p.cmd.Process.Kill()
-		return fmt.Errorf("accept timeout")
+	case <-time.After(acceptTimeout):
+		_ = p.launcher.Signal(syscall.SIGKILL)
+		return withRejectErr(fmt.Errorf("accept timeout"))
 	case <-p.cmdDone:
-		return fmt.Errorf("cmd exited before accepting connection: %w", p.cmdErr)
-	case r := <-res:
-		if r.err != nil {
-			_ = p.cmd.Process.Kill()
-			return fmt.Errorf("accept: %w", r.err)
+		return withRejectErr(fmt.Errorf("cmd exited before accepting connection: %w", p.exitErr))
+	case err := <-acceptErr:
+		_ = p.launcher.Signal(syscall.SIGKILL)
+		return fmt.Errorf("accept: %w", err)
+	case conn := <-accepted:
+		p.conn = conn
+		p.logger.Info("child connected", "socket", p.socketPath)
+		if p.metrics != nil {
+			p.metrics.AddCounter(MetricHandshakes, Labels{}, 1)
 		}
-		p.conn = r.conn
+		p.connected()
 		go p.readConn()
 	}
 	return nil
 }
 
+func (p *ParentIPC) checkConn(conn net.Conn, timeout time.Duration) error {
+	if p.peerCheck != PeerCheckNone {
+		if err := checkPeerCred(conn, p.peerCheck, p.launcher.Pid(), cmdUid(p.cmd)); err != nil {
+			return err
+		}
+	}
+	return authenticate(conn, p.token, timeout)
+}
+
 func (p *ParentIPC) Stop() error {
 	p.mu.Lock()
 	hasPending := len(p.pendingCalls) > 0
@@ -119,51 +314,89 @@ func (p *ParentIPC) Stop() error {
 		return fmt.Errorf("there are calls processing")
 	}
 	p.stopRequested.Store(true)
-	if err := p.cmd.Process.Signal(syscall.SIGINT); err != nil {
-		return fmt.Errorf("send SIGINT: %w", err)
+	if err := p.interrupt(); err != nil {
+		return err
 	}
 	return p.Wait()
 }
 
-func (p *ParentIPC) Wait(timeout ...time.Duration) (retErr error) {
+// Wait waits for the child to exit. A failed child is reported with *ChildExitError and broken
+// communication with *IPCError, joined if both happen.
+func (p *ParentIPC) Wait(timeout ...time.Duration) error {
 	const maxDuration = time.Duration(1<<63 - 1)
 	_timeout := maxDuration
 	if len(timeout) > 0 {
 		_timeout = timeout[0]
 	}
 
+	var errs []error
+	timedOut := time.After(_timeout)
 loop:
 	for {
 		select {
-		case err := <-p.errCh:
-			retErr = mergeErr(retErr, fmt.Errorf("ipc internal error: %w", err))
+		case <-p.errCh:
+			errs = append(errs, p.ipcError())
 			break loop
 		case <-p.cmdDone:
-			err := p.cmdErr
-			if err != nil {
-				var exitErr *exec.ExitError
-				if ok := errors.As(err, &exitErr); ok {
-					if !exitErr.Success() {
-						ws, ok := exitErr.Sys().(syscall.WaitStatus)
-						if !(ok && ws.Signaled() && ws.Signal() == syscall.SIGINT && p.stopRequested.Load()) {
-							retErr = mergeErr(retErr, fmt.Errorf("cmd wait: %w", err))
-						}
-					}
-				} else {
-					retErr = mergeErr(retErr, fmt.Errorf("cmd wait: %w", err))
-				}
+			if p.exit.Err != nil && !p.exit.stopped() {
+				errs = append(errs, p.exitErr)
 			}
 			break loop
-		case <-time.After(_timeout):
+		case <-timedOut:
+			// The child is interrupted once, then Wait goes on until it exits
+			timedOut = nil
 			p.stopRequested.Store(true)
-			if err := p.cmd.Process.Signal(syscall.SIGINT); err != nil {
-				retErr = mergeErr(retErr, fmt.Errorf("send SIGINT: %w", err))
+			if err := p.interrupt(); err != nil {
+				errs = append(errs, err)
 			}
 		}
 	}
 
 	p.closeConn()
-	_ = os.Remove(p.socketPath)
+	p.removeSocketDir()
 
-	return retErr
+	return errors.Join(errs...)
+}
+
+// interrupt sends SIGINT to the child and kills it if it is still running after the grace period.
+// With a process group, the group is killed anyway, so grandchildren ignoring SIGINT do not survive.
+// Only the first call signals the child, later ones return its result.
+func (p *ParentIPC) interrupt() error {
+	p.interruptOnce.Do(func() {
+		p.interruptErr = p.signalInterrupt()
+	})
+	return p.interruptErr
+}
+
+func (p *ParentIPC) signalInterrupt() error {
+	if err := p.launcher.Signal(syscall.SIGINT); err != nil {
+		return fmt.Errorf("send SIGINT: %w", err)
+	}
+	if p.killGracePeriod < 0 {
+		return nil
+	}
+	go func() {
+		timer := time.NewTimer(p.killGracePeriod)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			p.logger.Warn("child did not exit after SIGINT, killing", "pid", p.launcher.Pid())
+		case <-p.cmdDone:
+			if !p.processGroup {
+				return
+			}
+			<-timer.C
+		}
+		if err := p.launcher.Signal(syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) && !errors.Is(err, os.ErrProcessDone) {
+			p.logger.Warn("kill child", "pid", p.launcher.Pid(), "error", err)
+		}
+	}()
+	return nil
+}
+
+// removeSocketDir removes the socket file with its directory. Abstract sockets have none.
+func (p *ParentIPC) removeSocketDir() {
+	if p.socketDir != "" {
+		_ = os.RemoveAll(p.socketDir)
+	}
 }
@@ -0,0 +1,8 @@
+package golang
+
+import "syscall"
+
+func setParentDeathSignal(attr *syscall.SysProcAttr, sig syscall.Signal) error {
+	attr.Pdeathsig = sig
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build unix && !linux
+
+package golang
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+func setParentDeathSignal(attr *syscall.SysProcAttr, sig syscall.Signal) error {
+	return fmt.Errorf("parent death signal is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,52 @@
+package golang
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// Peer is an ipc endpoint over an already established connection, e.g. one end of net.Pipe.
+// ParentIPC and ChildIPC create their connections themselves; Peer is mostly useful for tests, see kittenipctest.
+type Peer struct {
+	*ipcCommon
+	done chan struct{}
+}
+
+func NewPeer(conn net.Conn, opts *Options, localApis ...any) *Peer {
+	p := Peer{
+		ipcCommon: newIpcCommon(context.Background(), opts, localApis),
+		done:      make(chan struct{}),
+	}
+	p.conn = conn
+	return &p
+}
+
+func (p *Peer) Start() {
+	p.connected()
+	go func() {
+		p.readConn()
+		close(p.done)
+	}()
+}
+
+// Stop closes the connection. Calls still pending fail.
+func (p *Peer) Stop() error {
+	if p.processingIncomingCalls.Load() > 0 {
+		return fmt.Errorf("there are calls processing")
+	}
+	p.stopRequested.Store(true)
+	p.closeConn()
+	<-p.done
+	return nil
+}
+
+// Wait blocks until the connection is closed by either side or an ipc error occurs.
+func (p *Peer) Wait() error {
+	select {
+	case <-p.errCh:
+		return p.ipcError()
+	case <-p.done:
+		return nil
+	}
+}
@@ -0,0 +1,28 @@
+package golang
+
+import "fmt"
+
+// PeerCheck selects how ParentIPC verifies credentials of the connected peer. Only supported on Linux.
+type PeerCheck int
+
+const (
+	PeerCheckNone PeerCheck = iota
+	// PeerCheckPid accepts only the spawned child process running as the expected user.
+	PeerCheckPid
+	// PeerCheckDescendant also accepts descendants of the child, e.g. when the command is a shell script.
+	PeerCheckDescendant
+)
+
+// PeerCredError is returned when credentials of a connected peer do not match the child.
+type PeerCredError struct {
+	Pid         int
+	Uid         int
+	ExpectedPid int
+	ExpectedUid int
+	Reason      string
+}
+
+func (e *PeerCredError) Error() string {
+	return fmt.Sprintf("peer credentials rejected: %s (pid=%d uid=%d, expected pid=%d uid=%d)",
+		e.Reason, e.Pid, e.Uid, e.ExpectedPid, e.ExpectedUid)
+}
@@ -0,0 +1,102 @@
+package golang
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+const peerCredSupported = true
+
+func peerCred(conn net.Conn) (*syscall.Ucred, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, fmt.Errorf("not a unix connection: %T", conn)
+	}
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return nil, fmt.Errorf("get raw connection: %w", err)
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	err = rawConn.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("control raw connection: %w", err)
+	}
+	if credErr != nil {
+		return nil, fmt.Errorf("getsockopt SO_PEERCRED: %w", credErr)
+	}
+	return cred, nil
+}
+
+// cmdUid returns the user the command runs as. Children of other launchers are expected to run as the current user.
+func cmdUid(cmd *exec.Cmd) int {
+	if cmd != nil && cmd.SysProcAttr != nil && cmd.SysProcAttr.Credential != nil {
+		return int(cmd.SysProcAttr.Credential.Uid)
+	}
+	return os.Getuid()
+}
+
+func checkPeerCred(conn net.Conn, check PeerCheck, childPid, childUid int) error {
+	cred, err := peerCred(conn)
+	if err != nil {
+		return err
+	}
+
+	credErr := &PeerCredError{
+		Pid:         int(cred.Pid),
+		Uid:         int(cred.Uid),
+		ExpectedPid: childPid,
+		ExpectedUid: childUid,
+	}
+	if credErr.Uid != childUid {
+		credErr.Reason = "uid mismatch"
+		return credErr
+	}
+	if credErr.Pid == childPid {
+		return nil
+	}
+	if check == PeerCheckDescendant && isDescendant(credErr.Pid, childPid) {
+		return nil
+	}
+	credErr.Reason = "pid mismatch"
+	return credErr
+}
+
+// isDescendant walks parent pids in /proc up to init.
+func isDescendant(pid, ancestor int) bool {
+	for pid > 1 {
+		ppid, err := parentPid(pid)
+		if err != nil {
+			return false
+		}
+		if ppid == ancestor {
+			return true
+		}
+		pid = ppid
+	}
+	return false
+}
+
+func parentPid(pid int) (int, error) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	// The command name may contain spaces and parentheses, fields after it are "state ppid ..."
+	end := bytes.LastIndexByte(stat, ')')
+	if end < 0 {
+		return 0, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	fields := bytes.Fields(stat[end+1:])
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	return strconv.Atoi(string(fields[1]))
+}
@@ -0,0 +1,88 @@
+package golang
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// selfConn returns the accepted side of a unix connection made by this process.
+func selfConn(t *testing.T) net.Conn {
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "test.sock"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	client, err := net.Dial("unix", listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestCheckPeerCred(t *testing.T) {
+	conn := selfConn(t)
+
+	t.Run("matching pid", func(t *testing.T) {
+		assert.NoError(t, checkPeerCred(conn, PeerCheckPid, os.Getpid(), os.Getuid()))
+	})
+
+	t.Run("pid mismatch", func(t *testing.T) {
+		err := checkPeerCred(conn, PeerCheckPid, os.Getppid(), os.Getuid())
+		var credErr *PeerCredError
+		require.ErrorAs(t, err, &credErr)
+		assert.Equal(t, "pid mismatch", credErr.Reason)
+		assert.Equal(t, os.Getpid(), credErr.Pid)
+		assert.Equal(t, os.Getppid(), credErr.ExpectedPid)
+	})
+
+	t.Run("uid mismatch", func(t *testing.T) {
+		err := checkPeerCred(conn, PeerCheckPid, os.Getpid(), os.Getuid()+1)
+		var credErr *PeerCredError
+		require.ErrorAs(t, err, &credErr)
+		assert.Equal(t, "uid mismatch", credErr.Reason)
+	})
+
+	t.Run("descendant", func(t *testing.T) {
+		assert.NoError(t, checkPeerCred(conn, PeerCheckDescendant, os.Getppid(), os.Getuid()))
+	})
+}
+
+func TestParentPeerCheck(t *testing.T) {
+	metrics := NewMemoryMetrics()
+	p, err := NewParent(exec.Command("sh", "-c", "exec sleep 15"), &Options{PeerCheck: PeerCheckPid, Metrics: metrics})
+	require.NoError(t, err)
+
+	started := make(chan error, 1)
+	go func() {
+		started <- p.Start()
+	}()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(p.socketPath)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The right token from the wrong process is still rejected
+	conn, err := net.Dial("unix", p.socketPath)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, sendHandshake(conn, p.token))
+	require.Eventually(t, func() bool {
+		return metrics.Counter(MetricHandshakeFailures, Labels{}) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_ = p.cmd.Process.Kill()
+	err = <-started
+	var credErr *PeerCredError
+	require.ErrorAs(t, err, &credErr)
+	assert.Equal(t, os.Getpid(), credErr.Pid)
+	assert.Equal(t, p.cmd.Process.Pid, credErr.ExpectedPid)
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package golang
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"runtime"
+)
+
+const peerCredSupported = false
+
+func cmdUid(cmd *exec.Cmd) int {
+	return -1
+}
+
+func checkPeerCred(conn net.Conn, check PeerCheck, childPid, childUid int) error {
+	return fmt.Errorf("peer credentials are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,30 @@
+package golang
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupProcess(t *testing.T) {
+	t.Run("own process group by default", func(t *testing.T) {
+		cmd := exec.Command("true")
+		p, err := NewParent(cmd, nil)
+		require.NoError(t, err)
+		assert.True(t, p.processGroup)
+		assert.True(t, cmd.SysProcAttr.Setpgid)
+		assert.Equal(t, defaultKillGracePeriod, p.killGracePeriod)
+	})
+
+	t.Run("shared process group, parent death signal", func(t *testing.T) {
+		cmd := exec.Command("true")
+		p, err := NewParent(cmd, &Options{SharedProcessGroup: true, ParentDeathSignal: syscall.SIGKILL})
+		require.NoError(t, err)
+		assert.False(t, p.processGroup)
+		assert.False(t, cmd.SysProcAttr.Setpgid)
+		assert.Equal(t, syscall.SIGKILL, cmd.SysProcAttr.Pdeathsig)
+	})
+}
@@ -0,0 +1,24 @@
+//go:build !unix
+
+package golang
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"syscall"
+)
+
+func setupProcess(cmd *exec.Cmd, opts *Options) (processGroup bool, err error) {
+	if opts.ParentDeathSignal != 0 {
+		return false, fmt.Errorf("parent death signal is not supported on %s", runtime.GOOS)
+	}
+	return false, nil
+}
+
+func (l *ExecLauncher) Signal(sig syscall.Signal) error {
+	if sig == syscall.SIGKILL {
+		return l.Cmd.Process.Kill()
+	}
+	return l.Cmd.Process.Signal(sig)
+}
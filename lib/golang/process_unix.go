@@ -0,0 +1,36 @@
+//go:build unix
+
+package golang
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func setupProcess(cmd *exec.Cmd, opts *Options) (processGroup bool, err error) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	if !opts.SharedProcessGroup {
+		cmd.SysProcAttr.Setpgid = true
+		processGroup = true
+	}
+	if opts.ParentDeathSignal != 0 {
+		if err := setParentDeathSignal(cmd.SysProcAttr, opts.ParentDeathSignal); err != nil {
+			return false, err
+		}
+	}
+	return processGroup, nil
+}
+
+// Signal signals the process group of the child if ProcessGroup is set, or only the child otherwise.
+func (l *ExecLauncher) Signal(sig syscall.Signal) error {
+	if l.ProcessGroup {
+		pgid := l.Cmd.Process.Pid
+		if l.Cmd.SysProcAttr != nil && l.Cmd.SysProcAttr.Pgid != 0 {
+			pgid = l.Cmd.SysProcAttr.Pgid
+		}
+		return syscall.Kill(-pgid, sig)
+	}
+	return l.Cmd.Process.Signal(sig)
+}
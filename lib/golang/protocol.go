@@ -1,8 +1,14 @@
 package golang
 
+import "time"
+
 const ipcSocketArg = "--ipc-socket"
-const maxMessageLength = 1 << 30 // 1 GB
-const defaultAcceptTimeout = 10 // seconds
+const ipcSocketEnv = "KITTEN_IPC_SOCKET"
+const ipcTokenEnv = "KITTEN_IPC_TOKEN"
+const defaultMaxMessageSize = 1 << 30 // 1 GB
+const defaultAcceptTimeout = 10 * time.Second
+const childWaitDelay = time.Second
+const defaultKillGracePeriod = 5 * time.Second
 
 type MsgType int
 
@@ -11,13 +17,32 @@ type Vals []any
 const (
 	MsgCall     MsgType = 1
 	MsgResponse MsgType = 2
+	// MsgHandshake is the first message of the child, authenticating it with the token.
+	MsgHandshake MsgType = 3
+	// MsgChunk is a part of a message larger than the frame size, see Options.FrameSize.
+	MsgChunk MsgType = 4
 )
 
 type Message struct {
-	Type   MsgType `json:"type"`
-	Id     int64   `json:"id"`
-	Method string  `json:"method"`
-	Args   Vals    `json:"args"`
-	Result Vals    `json:"result"`
-	Error  string  `json:"error"`
+	Type     MsgType       `json:"type"`
+	Id       int64         `json:"id"`
+	Method   string        `json:"method"`
+	Args     Vals          `json:"args"`
+	Metadata Metadata      `json:"metadata,omitempty"`
+	Result   Vals          `json:"result"`
+	Error    *ErrorPayload `json:"error,omitempty"`
+	Token    string        `json:"token,omitempty"`
+
+	size int // size of received message in bytes
+}
+
+// ErrorPayload is the wire representation of an error returned by a call.
+type ErrorPayload struct {
+	Code    string         `json:"code,omitempty"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+	Cause   *ErrorPayload  `json:"cause,omitempty"`
+	// Causes are set instead of Cause for errors wrapping several errors, like errors.Join.
+	Causes []*ErrorPayload `json:"causes,omitempty"`
+	Stack  string          `json:"stack,omitempty"`
 }
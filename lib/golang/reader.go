@@ -0,0 +1,151 @@
+package golang
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// oversizedPrefix is how much of a skipped message is kept to find out its type and id.
+const oversizedPrefix = 256
+
+// oversizedSuffix is how much of the end of a skipped message is kept, enough for the last flag of a chunk.
+const oversizedSuffix = 16
+
+// messageReader reads newline separated messages. Unlike bufio.Scanner it skips messages
+// over the limit instead of failing, so the connection survives them.
+type messageReader struct {
+	rd    *bufio.Reader
+	limit int
+	buf   []byte
+}
+
+// oversizedMessage is a skipped message, known by its size, beginning and end.
+type oversizedMessage struct {
+	size   int
+	prefix []byte
+	suffix []byte
+}
+
+func newMessageReader(r io.Reader, limit int) *messageReader {
+	return &messageReader{rd: bufio.NewReaderSize(r, 64*1024), limit: limit}
+}
+
+// next returns the next message, which is valid until the following call, or describes it if it is over the limit.
+func (r *messageReader) next() ([]byte, *oversizedMessage, error) {
+	r.buf = r.buf[:0]
+	var oversized *oversizedMessage
+	for {
+		chunk, err := r.rd.ReadSlice('\n')
+		if oversized != nil {
+			oversized.size += len(chunk)
+			oversized.prefix = appendPrefix(oversized.prefix, chunk)
+			oversized.suffix = appendSuffix(oversized.suffix, chunk)
+		} else {
+			r.buf = append(r.buf, chunk...)
+			if len(bytes.TrimSuffix(r.buf, []byte{'\n'})) > r.limit {
+				oversized = &oversizedMessage{
+					size:   len(r.buf),
+					prefix: appendPrefix(nil, r.buf),
+					suffix: appendSuffix(nil, r.buf),
+				}
+				r.buf = r.buf[:0]
+			}
+		}
+
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err == io.EOF && (oversized != nil || len(r.buf) > 0) {
+			// The last message has no newline, EOF is returned by the following call
+			err = nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if oversized != nil {
+			if bytes.HasSuffix(chunk, []byte{'\n'}) {
+				oversized.size--
+			}
+			oversized.suffix = bytes.TrimRight(oversized.suffix, "\r\n")
+			return nil, oversized, nil
+		}
+		msg := bytes.TrimSuffix(r.buf, []byte{'\n'})
+		return bytes.TrimSuffix(msg, []byte{'\r'}), nil, nil
+	}
+}
+
+func appendPrefix(prefix, data []byte) []byte {
+	if missing := oversizedPrefix - len(prefix); missing > 0 {
+		prefix = append(prefix, data[:min(len(data), missing)]...)
+	}
+	return prefix
+}
+
+func appendSuffix(suffix, data []byte) []byte {
+	suffix = append(suffix, data[max(0, len(data)-oversizedSuffix):]...)
+	return suffix[max(0, len(suffix)-oversizedSuffix):]
+}
+
+// messageHeader parses type and id from the beginning of a message. Both sides write them first.
+func messageHeader(prefix []byte) (msgType MsgType, id int64, ok bool) {
+	dec := json.NewDecoder(bytes.NewReader(prefix))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return 0, 0, false
+	}
+	var hasType, hasId bool
+	for !hasType || !hasId {
+		key, err := dec.Token()
+		if err != nil {
+			return 0, 0, false
+		}
+		val, err := dec.Token()
+		if err != nil {
+			return 0, 0, false
+		}
+		num, isNum := val.(json.Number)
+		switch key {
+		case "type", "id":
+			if !isNum {
+				return 0, 0, false
+			}
+			n, err := num.Int64()
+			if err != nil {
+				return 0, 0, false
+			}
+			if key == "type" {
+				msgType, hasType = MsgType(n), true
+			} else {
+				id, hasId = n, true
+			}
+		default:
+			if _, nested := val.(json.Delim); nested {
+				return 0, 0, false
+			}
+		}
+	}
+	return msgType, id, true
+}
+
+// rejectOversized answers a skipped call with ErrMessageTooLarge, or fails the call a skipped response belongs to.
+func (ipc *ipcCommon) rejectOversized(msg *oversizedMessage) {
+	err := ErrMessageTooLarge.Errorf("message of %d bytes exceeds limit of %d bytes", msg.size, ipc.maxMessageSize)
+	msgType, id, ok := messageHeader(msg.prefix)
+	switch {
+	case !ok:
+		ipc.raiseErr(SeverityWarning, fmt.Errorf("skipped message: %w", err))
+	case msgType == MsgCall:
+		ipc.raiseErr(SeverityWarning, fmt.Errorf("rejected call id=%d: %w", id, err))
+		go ipc.sendResponse(id, LaneNormal, nil, err)
+	case msgType == MsgResponse:
+		ipc.raiseErr(SeverityWarning, fmt.Errorf("rejected response id=%d: %w", id, err))
+		ipc.completeCall(id, callResult{err: err, size: msg.size})
+	default:
+		ipc.raiseErr(SeverityWarning, fmt.Errorf("skipped message of type %d: %w", msgType, err))
+	}
+}
@@ -0,0 +1,124 @@
+package golang
+
+import (
+	"bufio"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageReader(t *testing.T) {
+	input := "short\r\n" + strings.Repeat("x", 20) + "\n" + `{"type":1,"id":7,"args":["` + strings.Repeat("y", 300) + `"]}` + "\nlast"
+	rd := newMessageReader(strings.NewReader(input), 10)
+
+	msg, oversized, err := rd.next()
+	require.NoError(t, err)
+	assert.Nil(t, oversized)
+	assert.Equal(t, "short", string(msg))
+
+	_, oversized, err = rd.next()
+	require.NoError(t, err)
+	require.NotNil(t, oversized)
+	assert.Equal(t, 20, oversized.size)
+
+	_, oversized, err = rd.next()
+	require.NoError(t, err)
+	require.NotNil(t, oversized)
+	assert.Equal(t, 329, oversized.size)
+	assert.Len(t, oversized.prefix, oversizedPrefix)
+
+	msg, _, err = rd.next()
+	require.NoError(t, err)
+	assert.Equal(t, "last", string(msg))
+
+	_, _, err = rd.next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestMessageReaderPrefix(t *testing.T) {
+	input := `{"type":2,"id":12345,"result":["` + strings.Repeat("z", 100) + `"]}` + "\n"
+	rd := &messageReader{rd: bufio.NewReaderSize(strings.NewReader(input), 16), limit: 10}
+
+	_, oversized, err := rd.next()
+	require.NoError(t, err)
+	require.NotNil(t, oversized)
+	assert.Equal(t, len(input)-1, oversized.size)
+	typ, id, ok := messageHeader(oversized.prefix)
+	assert.True(t, ok)
+	assert.Equal(t, MsgResponse, typ)
+	assert.Equal(t, int64(12345), id)
+}
+
+func TestMessageHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		typ    MsgType
+		id     int64
+		ok     bool
+	}{
+		{"go", `{"type":1,"id":42,"method":"A.B","args":["xx`, MsgCall, 42, true},
+		{"spaces", `{ "type": 2, "id": 7, "result": [`, MsgResponse, 7, true},
+		{"id first", `{"id":3,"type":1,"args":[`, MsgCall, 3, true},
+		{"nested before id", `{"type":1,"args":["x"],"id":3}`, 0, 0, false},
+		{"truncated", `{"type":1,"i`, 0, 0, false},
+		{"garbage", `xxxxx`, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typ, id, ok := messageHeader([]byte(tt.prefix))
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.typ, typ)
+			assert.Equal(t, tt.id, id)
+		})
+	}
+}
+
+type echoEndpoint struct{}
+
+func (e *echoEndpoint) Echo(s string) (string, error) {
+	return s, nil
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	big := strings.Repeat("x", 1000)
+
+	t.Run("call rejected", func(t *testing.T) {
+		a, _ := newTestPair(t, nil, &Options{MaxMessageSize: 500}, nil, []any{&echoEndpoint{}})
+
+		_, err := a.Call("echoEndpoint.Echo", big)
+		assert.ErrorIs(t, err, ErrMessageTooLarge)
+
+		res, err := a.Call("echoEndpoint.Echo", "small")
+		require.NoError(t, err)
+		assert.Equal(t, "small", res[0])
+	})
+
+	t.Run("response rejected", func(t *testing.T) {
+		a, b := newTestPair(t, &Options{MaxMessageSize: 500}, nil, nil, []any{&echoEndpoint{}})
+
+		// The call fits, its response does not
+		_, err := a.Call("echoEndpoint.Echo", big[:400]+big[:400])
+		assert.ErrorIs(t, err, ErrMessageTooLarge)
+		assert.Empty(t, a.errCh)
+		assert.Empty(t, b.errCh)
+
+		res, err := a.Call("echoEndpoint.Echo", "small")
+		require.NoError(t, err)
+		assert.Equal(t, "small", res[0])
+	})
+}
+
+func TestAcceptTimeout(t *testing.T) {
+	p, err := NewParent(exec.Command("sh", "-c", "exec sleep 15"), &Options{AcceptTimeout: 200 * time.Millisecond})
+	require.NoError(t, err)
+
+	start := time.Now()
+	assert.ErrorContains(t, p.Start(), "accept timeout")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
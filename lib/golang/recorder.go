@@ -0,0 +1,40 @@
+package golang
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Direction string
+
+const (
+	DirectionSent     Direction = "send"
+	DirectionReceived Direction = "recv"
+)
+
+// RecordEntry is a line of a recording written to Options.Recorder.
+type RecordEntry struct {
+	Time      time.Time `json:"time"`
+	Direction Direction `json:"dir"`
+	Message   Message   `json:"msg"`
+}
+
+// record writes a message to the recorder. Write errors are logged and do not affect ipc.
+func (ipc *ipcCommon) record(dir Direction, msg Message) {
+	if ipc.recorder == nil {
+		return
+	}
+
+	data, err := json.Marshal(RecordEntry{Time: time.Now(), Direction: dir, Message: msg})
+	if err != nil {
+		ipc.logger.Warn("marshal record entry", "error", err)
+		return
+	}
+	data = append(data, '\n')
+
+	ipc.recordMu.Lock()
+	defer ipc.recordMu.Unlock()
+	if _, err := ipc.recorder.Write(data); err != nil {
+		ipc.logger.Warn("write record entry", "error", err)
+	}
+}
@@ -0,0 +1,182 @@
+package golang
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// ErrUnexpectedCall is returned by Replay for calls not found in the recording.
+var ErrUnexpectedCall = errors.New("unexpected call")
+
+type ReplayMatch int
+
+const (
+	// ReplayMatchArgs matches recorded calls by method and arguments.
+	ReplayMatchArgs ReplayMatch = iota
+	// ReplayMatchMethod matches recorded calls by method only.
+	ReplayMatchMethod
+)
+
+type ReplayOptions struct {
+	Match ReplayMatch
+}
+
+type replayCall struct {
+	call     Message
+	response *Message
+	used     bool
+}
+
+// Replay answers calls from a recording made with Options.Recorder instead of a remote process.
+// It implements IpcCommon, so generated remote APIs can use it in tests.
+// Every recorded call is answered once, in recording order. Calls the remote process made are ignored.
+type Replay struct {
+	ipc        *ipcCommon
+	match      ReplayMatch
+	mu         sync.Mutex
+	calls      []*replayCall
+	unexpected []Message
+}
+
+// NewReplay reads a recording from r.
+func NewReplay(r io.Reader, opts *ReplayOptions) (*Replay, error) {
+	if opts == nil {
+		opts = &ReplayOptions{}
+	}
+	rp := &Replay{
+		ipc:   &ipcCommon{},
+		match: opts.Match,
+	}
+
+	byId := make(map[int64]*replayCall)
+	// Lines are not limited, an entry is larger than its message and messages may exceed the default limit
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("read recording: %w", err)
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			if err != nil {
+				break
+			}
+			continue
+		}
+		var entry RecordEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("unmarshal record entry at line %d: %w", line, err)
+		}
+		switch {
+		case entry.Direction == DirectionSent && entry.Message.Type == MsgCall:
+			call := &replayCall{call: entry.Message}
+			rp.calls = append(rp.calls, call)
+			byId[entry.Message.Id] = call
+		case entry.Direction == DirectionReceived && entry.Message.Type == MsgResponse:
+			if call, ok := byId[entry.Message.Id]; ok {
+				call.response = &entry.Message
+				delete(byId, entry.Message.Id)
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	return rp, nil
+}
+
+func (rp *Replay) Call(method string, params ...any) (Vals, error) {
+	return rp.CallContext(context.Background(), method, params...)
+}
+
+func (rp *Replay) CallContext(ctx context.Context, method string, params ...any) (Vals, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	args, err := rp.normalizeArgs(params)
+	if err != nil {
+		return nil, err
+	}
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	for _, call := range rp.calls {
+		if call.used || !rp.matches(call.call, method, args) {
+			continue
+		}
+		call.used = true
+		if call.response == nil {
+			return nil, fmt.Errorf("no response recorded for call id=%d", call.call.Id)
+		}
+		if call.response.Error != nil {
+			return nil, call.response.Error.remoteError()
+		}
+		return call.response.Result, nil
+	}
+
+	rp.unexpected = append(rp.unexpected, Message{Type: MsgCall, Method: method, Args: args})
+	return nil, fmt.Errorf("%w: %s", ErrUnexpectedCall, method)
+}
+
+func (rp *Replay) ConvType(needType, gotType reflect.Type, arg any) any {
+	return rp.ipc.ConvType(needType, gotType, arg)
+}
+
+// Unexpected returns calls that did not match the recording.
+func (rp *Replay) Unexpected() []Message {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return append([]Message(nil), rp.unexpected...)
+}
+
+// Unused returns recorded calls that were not made.
+func (rp *Replay) Unused() []Message {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	var unused []Message
+	for _, call := range rp.calls {
+		if !call.used {
+			unused = append(unused, call.call)
+		}
+	}
+	return unused
+}
+
+// normalizeArgs serializes params as they would be sent, so they compare equal to recorded ones.
+func (rp *Replay) normalizeArgs(params []any) (Vals, error) {
+	args := make(Vals, len(params))
+	for i := range params {
+		args[i] = rp.ipc.serialize(params[i])
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("marshal args: %w", err)
+	}
+	var normalized Vals
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("unmarshal args: %w", err)
+	}
+	return normalized, nil
+}
+
+func (rp *Replay) matches(recorded Message, method string, args Vals) bool {
+	if recorded.Method != method {
+		return false
+	}
+	if rp.match == ReplayMatchMethod {
+		return true
+	}
+	if len(recorded.Args) == 0 && len(args) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(recorded.Args, args)
+}
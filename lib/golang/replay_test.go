@@ -0,0 +1,100 @@
+package golang
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recordSession(t *testing.T) []byte {
+	var rec syncBuffer
+	a, _ := newTestPair(t, &Options{Recorder: &rec}, nil, nil, []any{&testEndpoint{}, &errorsEndpoint{}, &blobEndpoint{}})
+
+	_, err := a.Call("testEndpoint.Hello", "alice")
+	require.NoError(t, err)
+	_, err = a.Call("testEndpoint.Hello", "bob")
+	require.NoError(t, err)
+	_, err = a.Call("errorsEndpoint.Find", 7)
+	require.Error(t, err)
+	_, err = a.Call("blobEndpoint.Store", "token", []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return bytes.Clone(rec.buf.Bytes())
+}
+
+func TestRecorder(t *testing.T) {
+	data := recordSession(t)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 8)
+	assert.Contains(t, lines[0], `"dir":"send"`)
+	assert.Contains(t, lines[0], `"method":"testEndpoint.Hello"`)
+	assert.Contains(t, lines[1], `"dir":"recv"`)
+	assert.Contains(t, lines[1], `"result":["hello alice"]`)
+}
+
+func TestReplay(t *testing.T) {
+	data := recordSession(t)
+
+	t.Run("match args", func(t *testing.T) {
+		rp, err := NewReplay(bytes.NewReader(data), nil)
+		require.NoError(t, err)
+
+		vals, err := rp.Call("testEndpoint.Hello", "bob")
+		require.NoError(t, err)
+		assert.Equal(t, Vals{"hello bob"}, vals)
+
+		vals, err = rp.Call("testEndpoint.Hello", "alice")
+		require.NoError(t, err)
+		assert.Equal(t, Vals{"hello alice"}, vals)
+
+		_, err = rp.Call("errorsEndpoint.Find", 7)
+		assert.ErrorIs(t, err, errTestNotFound)
+
+		_, err = rp.Call("blobEndpoint.Store", "token", []byte{1, 2, 3})
+		assert.NoError(t, err)
+
+		_, err = rp.Call("testEndpoint.Hello", "carol")
+		assert.ErrorIs(t, err, ErrUnexpectedCall)
+		_, err = rp.Call("testEndpoint.Hello", "alice")
+		assert.ErrorIs(t, err, ErrUnexpectedCall)
+
+		unexpected := rp.Unexpected()
+		require.Len(t, unexpected, 2)
+		assert.Equal(t, Vals{"carol"}, unexpected[0].Args)
+		assert.Empty(t, rp.Unused())
+	})
+
+	t.Run("match method", func(t *testing.T) {
+		rp, err := NewReplay(bytes.NewReader(data), &ReplayOptions{Match: ReplayMatchMethod})
+		require.NoError(t, err)
+
+		vals, err := rp.Call("testEndpoint.Hello", "carol")
+		require.NoError(t, err)
+		assert.Equal(t, Vals{"hello alice"}, vals)
+
+		unused := rp.Unused()
+		require.Len(t, unused, 3)
+		assert.Equal(t, "testEndpoint.Hello", unused[0].Method)
+		assert.Empty(t, rp.Unexpected())
+	})
+
+	t.Run("no trailing newline", func(t *testing.T) {
+		rp, err := NewReplay(bytes.NewReader(bytes.TrimSuffix(data, []byte("\n"))), nil)
+		require.NoError(t, err)
+		require.Len(t, rp.Unused(), 4)
+		for _, call := range rp.calls {
+			assert.NotNil(t, call.response, call.call.Method)
+		}
+	})
+
+	t.Run("invalid recording", func(t *testing.T) {
+		_, err := NewReplay(strings.NewReader("{}\nnot json\n"), nil)
+		assert.ErrorContains(t, err, "line 2")
+	})
+}
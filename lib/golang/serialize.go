@@ -33,7 +33,12 @@ func (ipc *ipcCommon) ConvType(needType reflect.Type, gotType reflect.Type, arg
 	case reflect.Slice:
 		if needType.Elem().Kind() == reflect.Uint8 {
 			// Need []byte — incoming blob is a base64 string (from TS serialize)
-			if s, ok := arg.(string); ok {
+			// or a {"t":"blob","d":...} map (from Go serialize)
+			s, ok := arg.(string)
+			if m, isMap := arg.(map[string]any); isMap && m["t"] == "blob" {
+				s, ok = m["d"].(string)
+			}
+			if ok {
 				decoded, err := base64.StdEncoding.DecodeString(s)
 				if err == nil {
 					arg = decoded
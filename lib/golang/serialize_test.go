@@ -68,4 +68,10 @@ func TestConvType(t *testing.T) {
 		result := ipc.ConvType(reflect.TypeOf([]byte{}), reflect.TypeOf(""), "")
 		assert.Equal(t, []byte{}, result)
 	})
+
+	t.Run("blob map to []byte", func(t *testing.T) {
+		arg := map[string]any{"t": "blob", "d": "AQID"}
+		result := ipc.ConvType(reflect.TypeOf([]byte{}), reflect.TypeOf(arg), arg)
+		assert.Equal(t, []byte{0x01, 0x02, 0x03}, result)
+	})
 }
@@ -0,0 +1,106 @@
+package golang
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrorSeverity tells whether an internal error breaks the connection.
+type ErrorSeverity int
+
+const (
+	// SeverityWarning is a stray or late message, like a response to a call which has timed out.
+	// The connection keeps working.
+	SeverityWarning ErrorSeverity = iota
+	// SeverityFatal is a broken connection or a corrupted stream. Wait returns.
+	SeverityFatal
+)
+
+func (s ErrorSeverity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityFatal:
+		return "fatal"
+	default:
+		return fmt.Sprintf("ErrorSeverity(%d)", int(s))
+	}
+}
+
+// ErrorPolicy decides which internal errors make Wait return.
+type ErrorPolicy int
+
+const (
+	// ErrorPolicyDefault stops on fatal errors. Warnings are logged and passed to Options.OnError.
+	ErrorPolicyDefault ErrorPolicy = iota
+	// ErrorPolicyStrict treats warnings as fatal.
+	ErrorPolicyStrict
+)
+
+// InternalError is an internal ipc error, passed to Options.OnError and collected in IPCError.
+type InternalError struct {
+	Severity ErrorSeverity
+	Err      error
+}
+
+func (e *InternalError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InternalError) Unwrap() error {
+	return e.Err
+}
+
+// IPCError holds fatal internal errors, like an invalid message or a broken socket.
+// It unwraps into all of them, like errors.Join.
+type IPCError struct {
+	Errs []error
+}
+
+func (e *IPCError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return "ipc error: " + strings.Join(msgs, "\n")
+}
+
+func (e *IPCError) Unwrap() []error {
+	return e.Errs
+}
+
+// raiseErr reports an internal error. Fatal errors, after applying the error policy, make Wait return.
+func (ipc *ipcCommon) raiseErr(severity ErrorSeverity, err error) {
+	if ipc.errorPolicy == ErrorPolicyStrict {
+		severity = SeverityFatal
+	}
+	internalErr := &InternalError{Severity: severity, Err: err}
+
+	if severity == SeverityWarning {
+		ipc.logger.Warn("ipc error", "error", err, "severity", severity.String())
+	} else {
+		ipc.logger.Error("ipc error", "error", err, "severity", severity.String())
+	}
+	if ipc.onError != nil {
+		ipc.onError(internalErr)
+	}
+	if severity != SeverityFatal {
+		return
+	}
+
+	ipc.errMu.Lock()
+	ipc.errs = append(ipc.errs, internalErr)
+	ipc.errMu.Unlock()
+	select {
+	case ipc.errCh <- internalErr:
+	default:
+	}
+}
+
+// ipcError returns fatal errors raised so far.
+func (ipc *ipcCommon) ipcError() *IPCError {
+	ipc.errMu.Lock()
+	defer ipc.errMu.Unlock()
+	return &IPCError{Errs: slices.Clone(ipc.errs)}
+}
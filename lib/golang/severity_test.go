@@ -0,0 +1,50 @@
+package golang
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nanEndpoint struct{}
+
+func (e *nanEndpoint) NaN() (float64, error) {
+	return math.NaN(), nil
+}
+
+func TestErrorPolicy(t *testing.T) {
+	t.Run("late response is a warning", func(t *testing.T) {
+		a, b := newTestPair(t, nil, nil, nil, []any{&testEndpoint{}})
+		_, err := b.sendMsg(context.Background(), Message{Type: MsgResponse, Id: 100}, LaneNormal)
+		require.NoError(t, err)
+
+		res, err := a.Call("testEndpoint.Hello", "kitten")
+		require.NoError(t, err)
+		assert.Equal(t, "hello kitten", res[0])
+		assert.Empty(t, a.errCh)
+		assert.Empty(t, a.ipcError().Errs)
+	})
+
+	t.Run("strict policy", func(t *testing.T) {
+		a, b := newTestPair(t, &Options{ErrorPolicy: ErrorPolicyStrict}, nil, nil, nil)
+		_, err := b.sendMsg(context.Background(), Message{Type: MsgResponse, Id: 100}, LaneNormal)
+		require.NoError(t, err)
+
+		err = <-a.errCh
+		var internalErr *InternalError
+		require.ErrorAs(t, err, &internalErr)
+		assert.Equal(t, SeverityFatal, internalErr.Severity)
+		assert.Len(t, a.ipcError().Errs, 1)
+	})
+
+	t.Run("unmarshalable result", func(t *testing.T) {
+		a, b := newTestPair(t, nil, nil, nil, []any{&nanEndpoint{}})
+
+		_, err := a.Call("nanEndpoint.NaN")
+		assert.ErrorIs(t, err, ErrInternal)
+		assert.Empty(t, b.errCh)
+	})
+}
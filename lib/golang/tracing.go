@@ -0,0 +1,135 @@
+package golang
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// traceparentKey is the metadata key of W3C trace context. https://www.w3.org/TR/trace-context/
+const traceparentKey = "traceparent"
+
+type SpanKind int
+
+const (
+	SpanKindClient SpanKind = 1
+	SpanKindServer SpanKind = 2
+)
+
+// Tracer starts spans around calls. It is a small subset of OpenTelemetry API, so an adapter is trivial.
+type Tracer interface {
+	// Start starts a span as a child of the span in ctx. For server spans ctx carries
+	// the caller's span context, see RemoteSpanContextFromContext.
+	Start(ctx context.Context, name string, kind SpanKind) (context.Context, Span)
+}
+
+type Span interface {
+	SpanContext() SpanContext
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Traceparent formats span context as W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%x-%x-%02x", sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// ParseTraceparent parses W3C traceparent header value.
+func ParseTraceparent(traceparent string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, fmt.Errorf("invalid traceparent: %s", traceparent)
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, fmt.Errorf("invalid traceparent: %s", traceparent)
+	}
+	var flags [1]byte
+	for _, field := range []struct {
+		dst []byte
+		src string
+	}{
+		{sc.TraceID[:], parts[1]},
+		{sc.SpanID[:], parts[2]},
+		{flags[:], parts[3]},
+	} {
+		if hex.EncodedLen(len(field.dst)) != len(field.src) {
+			return sc, fmt.Errorf("invalid traceparent: %s", traceparent)
+		}
+		if _, err := hex.Decode(field.dst, []byte(field.src)); err != nil {
+			return sc, fmt.Errorf("invalid traceparent: %w", err)
+		}
+	}
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("invalid traceparent: %s", traceparent)
+	}
+	return sc, nil
+}
+
+type remoteSpanContextKey struct{}
+
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteSpanContextKey{}, sc)
+}
+
+// RemoteSpanContextFromContext returns span context of the caller of an incoming call.
+func RemoteSpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(remoteSpanContextKey{}).(SpanContext)
+	return sc, ok
+}
+
+func (ipc *ipcCommon) startClientSpan(ctx context.Context, method string) (context.Context, Span) {
+	ctx, span := ipc.tracer.Start(ctx, method, SpanKindClient)
+	span.SetAttributes(callAttributes(method)...)
+	if sc := span.SpanContext(); sc.IsValid() {
+		ctx = WithMetadata(ctx, Metadata{traceparentKey: sc.Traceparent()})
+	}
+	return ctx, span
+}
+
+func (ipc *ipcCommon) startServerSpan(ctx context.Context, method string) (context.Context, Span) {
+	if sc, err := ParseTraceparent(MetadataFromContext(ctx)[traceparentKey]); err == nil {
+		ctx = ContextWithRemoteSpanContext(ctx, sc)
+	}
+	ctx, span := ipc.tracer.Start(ctx, method, SpanKindServer)
+	span.SetAttributes(callAttributes(method)...)
+	return ctx, span
+}
+
+func callAttributes(method string) []Attribute {
+	endpoint, methodName, _ := strings.Cut(method, ".")
+	return []Attribute{
+		{Key: "rpc.system", Value: "kitten-ipc"},
+		{Key: "rpc.service", Value: endpoint},
+		{Key: "rpc.method", Value: methodName},
+	}
+}
+
+func endSpan(span Span, requestSize, responseSize int, err error) {
+	span.SetAttributes(
+		Attribute{Key: "rpc.request.size", Value: requestSize},
+		Attribute{Key: "rpc.response.size", Value: responseSize},
+	)
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
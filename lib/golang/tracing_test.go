@@ -0,0 +1,125 @@
+package golang
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedSpan struct {
+	tracer *recordingTracer
+	name   string
+	kind   SpanKind
+	sc     SpanContext
+	parent SpanContext
+	attrs  map[string]any
+	err    error
+}
+
+func (s *recordedSpan) SpanContext() SpanContext { return s.sc }
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) { s.err = err }
+
+func (s *recordedSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.ended = append(s.tracer.ended, s)
+}
+
+type spanKey struct{}
+
+// recordingTracer is an in-memory tracer keeping ended spans.
+type recordingTracer struct {
+	mu    sync.Mutex
+	ended []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, Span) {
+	span := &recordedSpan{tracer: t, name: name, kind: kind, attrs: make(map[string]any)}
+	if parent, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		span.parent = parent.sc
+	} else if remote, ok := RemoteSpanContextFromContext(ctx); ok {
+		span.parent = remote
+	}
+	span.sc.TraceID = span.parent.TraceID
+	if !span.parent.IsValid() {
+		_, _ = rand.Read(span.sc.TraceID[:])
+	}
+	_, _ = rand.Read(span.sc.SpanID[:])
+	span.sc.Flags = 1
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (t *recordingTracer) spans() []*recordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*recordedSpan(nil), t.ended...)
+}
+
+func TestTraceparent(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, err := ParseTraceparent(traceparent)
+	require.NoError(t, err)
+	assert.True(t, sc.IsValid())
+	assert.Equal(t, byte(1), sc.Flags)
+	assert.Equal(t, traceparent, sc.Traceparent())
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	} {
+		_, err := ParseTraceparent(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	clientTracer := &recordingTracer{}
+	serverTracer := &recordingTracer{}
+	a, _ := newTestPair(t, &Options{Tracer: clientTracer}, &Options{Tracer: serverTracer}, nil, []any{&testEndpoint{}, &errorsEndpoint{}})
+
+	_, err := a.Call("testEndpoint.Hello", "kitten")
+	require.NoError(t, err)
+	_, err = a.Call("errorsEndpoint.Find", 1)
+	require.Error(t, err)
+
+	clientSpans := clientTracer.spans()
+	require.Len(t, clientSpans, 2)
+	// server spans end after the response is sent
+	require.Eventually(t, func() bool { return len(serverTracer.spans()) == 2 }, time.Second, time.Millisecond)
+	serverSpans := serverTracer.spans()
+	if serverSpans[0].name != "testEndpoint.Hello" {
+		serverSpans[0], serverSpans[1] = serverSpans[1], serverSpans[0]
+	}
+
+	client, server := clientSpans[0], serverSpans[0]
+	assert.Equal(t, "testEndpoint.Hello", client.name)
+	assert.Equal(t, SpanKindClient, client.kind)
+	assert.Equal(t, SpanKindServer, server.kind)
+	assert.Equal(t, client.sc.TraceID, server.sc.TraceID)
+	assert.Equal(t, client.sc, server.parent)
+	assert.Equal(t, "testEndpoint", server.attrs["rpc.service"])
+	assert.Equal(t, "Hello", server.attrs["rpc.method"])
+	assert.Equal(t, client.attrs["rpc.request.size"], server.attrs["rpc.request.size"])
+	assert.Equal(t, client.attrs["rpc.response.size"], server.attrs["rpc.response.size"])
+	assert.Positive(t, client.attrs["rpc.request.size"])
+	assert.NoError(t, client.err)
+
+	assert.ErrorIs(t, clientSpans[1].err, errTestNotFound)
+	assert.ErrorIs(t, serverSpans[1].err, errTestNotFound)
+}
@@ -5,19 +5,6 @@ import (
 	"reflect"
 )
 
-func mergeErr(errs ...error) (ret error) {
-	for _, err := range errs {
-		if err != nil {
-			if ret == nil {
-				ret = err
-			} else {
-				ret = fmt.Errorf("%w; %w", ret, err)
-			}
-		}
-	}
-	return
-}
-
 func mapTypeNames(types []any) map[string]any {
 	result := make(map[string]any)
 	for _, t := range types {
@@ -1,31 +1,11 @@
 package golang
 
 import (
-	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestMergeErr(t *testing.T) {
-	t.Run("all nil returns nil", func(t *testing.T) {
-		assert.NoError(t, mergeErr(nil, nil, nil))
-	})
-
-	t.Run("single error returns it", func(t *testing.T) {
-		err := fmt.Errorf("one")
-		assert.EqualError(t, mergeErr(nil, err, nil), "one")
-	})
-
-	t.Run("multiple errors merged", func(t *testing.T) {
-		err1 := fmt.Errorf("one")
-		err2 := fmt.Errorf("two")
-		result := mergeErr(err1, err2)
-		assert.ErrorContains(t, result, "one")
-		assert.ErrorContains(t, result, "two")
-	})
-}
-
 func TestMapTypeNames(t *testing.T) {
 	type Foo struct{}
 	type Bar struct{}
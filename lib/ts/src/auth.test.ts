@@ -0,0 +1,66 @@
+import {test} from 'vitest';
+import * as fs from 'node:fs';
+import * as net from 'node:net';
+import * as os from 'node:os';
+import * as path from 'node:path';
+import * as readline from 'node:readline';
+import {authenticate, sendHandshake} from './auth.js';
+
+async function connectedPair(): Promise<[net.Socket, net.Socket, () => void]> {
+    const dir = fs.mkdtempSync(path.join(os.tmpdir(), 'kitten-ipc-test-'));
+    const socketPath = path.join(dir, 'test.sock');
+    const server = net.createServer();
+    await new Promise<void>((resolve) => server.listen(socketPath, resolve));
+    const serverConn = new Promise<net.Socket>((resolve) => server.once('connection', resolve));
+    const client = net.createConnection(socketPath);
+    const conn = await serverConn;
+    const cleanup = () => {
+        client.destroy();
+        conn.destroy();
+        server.close();
+        fs.rmSync(dir, {recursive: true, force: true});
+    };
+    return [conn, client, cleanup];
+}
+
+test('valid token, following data is kept', async ({expect}) => {
+    const [conn, client, cleanup] = await connectedPair();
+    try {
+        client.write(JSON.stringify({type: 3, token: 'secret'}) + '\nnext\n');
+        await authenticate(conn, 'secret', 1000);
+        const rl = readline.createInterface({input: conn});
+        const line = await new Promise<string>((resolve) => rl.once('line', resolve));
+        expect(line).toBe('next');
+    } finally {
+        cleanup();
+    }
+});
+
+test('invalid token', async ({expect}) => {
+    const [conn, client, cleanup] = await connectedPair();
+    try {
+        sendHandshake(client, 'guess');
+        await expect(authenticate(conn, 'secret', 1000)).rejects.toThrowError('invalid token');
+    } finally {
+        cleanup();
+    }
+});
+
+test('not a handshake', async ({expect}) => {
+    const [conn, client, cleanup] = await connectedPair();
+    try {
+        client.write(JSON.stringify({type: 1, id: 0, method: 'A.B', args: [], token: 'secret'}) + '\n');
+        await expect(authenticate(conn, 'secret', 1000)).rejects.toThrowError('expected handshake');
+    } finally {
+        cleanup();
+    }
+});
+
+test('silent peer times out', async ({expect}) => {
+    const [conn, , cleanup] = await connectedPair();
+    try {
+        await expect(authenticate(conn, 'secret', 50)).rejects.toThrowError('timed out');
+    } finally {
+        cleanup();
+    }
+});
@@ -0,0 +1,79 @@
+import * as crypto from 'node:crypto';
+import type * as net from 'node:net';
+import {type HandshakeMessage, MsgType} from './protocol.js';
+
+export const IPC_TOKEN_ENV = 'KITTEN_IPC_TOKEN';
+const MAX_HANDSHAKE_LENGTH = 4096;
+
+export function newToken(): string {
+    return crypto.randomBytes(32).toString('hex');
+}
+
+// The handshake is written directly to the socket, so the token is never logged.
+export function sendHandshake(conn: net.Socket, token: string): void {
+    const msg: HandshakeMessage = {type: MsgType.Handshake, token};
+    conn.write(JSON.stringify(msg) + '\n');
+}
+
+/**
+ * Reads the handshake of a connected child and checks its token.
+ * Data following the handshake is put back into the paused socket.
+ */
+export function authenticate(conn: net.Socket, token: string, timeoutMs: number): Promise<void> {
+    return new Promise((resolve, reject) => {
+        let buf = Buffer.alloc(0);
+
+        const finish = (err?: Error) => {
+            clearTimeout(timer);
+            conn.off('data', onData);
+            conn.off('error', onError);
+            conn.off('close', onClose);
+            conn.pause();
+            if (err) reject(err);
+            else resolve();
+        };
+        const onError = (err: Error) => finish(new Error(`read handshake: ${ err.message }`));
+        const onClose = () => finish(new Error('read handshake: connection closed'));
+        const onData = (chunk: Buffer) => {
+            buf = Buffer.concat([buf, chunk]);
+            const end = buf.indexOf(0x0a);
+            if (end === -1) {
+                if (buf.length > MAX_HANDSHAKE_LENGTH) {
+                    finish(new Error(`handshake is longer than ${ MAX_HANDSHAKE_LENGTH } bytes`));
+                }
+                return;
+            }
+            if (end > MAX_HANDSHAKE_LENGTH) {
+                finish(new Error(`handshake is longer than ${ MAX_HANDSHAKE_LENGTH } bytes`));
+                return;
+            }
+
+            let msg: Partial<HandshakeMessage>;
+            try {
+                msg = JSON.parse(buf.subarray(0, end).toString());
+            } catch (e) {
+                finish(new Error(`unmarshal handshake: ${ e }`));
+                return;
+            }
+            if (msg.type !== MsgType.Handshake) {
+                finish(new Error(`expected handshake, got message type ${ msg.type }`));
+                return;
+            }
+            const got = Buffer.from(typeof msg.token === 'string' ? msg.token : '');
+            const want = Buffer.from(token);
+            if (got.length !== want.length || !crypto.timingSafeEqual(got, want)) {
+                finish(new Error('invalid token'));
+                return;
+            }
+
+            finish();
+            const rest = buf.subarray(end + 1);
+            if (rest.length > 0) conn.unshift(rest);
+        };
+        const timer = setTimeout(() => finish(new Error('read handshake: timed out')), timeoutMs);
+
+        conn.on('data', onData);
+        conn.on('error', onError);
+        conn.on('close', onClose);
+    });
+}
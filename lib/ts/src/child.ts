@@ -1,15 +1,23 @@
 import * as net from 'node:net';
 import {IPCCommon, type IPCOptions} from './common.js';
-import {socketPathFromArgs} from './util.js';
+import {IPC_SOCKET_ENV, socketAddress, socketPathFromEnvOrArgs} from './util.js';
+import {IPC_TOKEN_ENV, sendHandshake} from './auth.js';
 
 export class ChildIPC extends IPCCommon {
+    private readonly token: string;
+
     constructor(opts?: IPCOptions, ...localApis: object[]) {
-        super(localApis, socketPathFromArgs(), opts);
+        super(localApis, socketPathFromEnvOrArgs(), opts);
+        // the socket and token are not inherited by processes the child starts
+        this.token = process.env[IPC_TOKEN_ENV] ?? '';
+        delete process.env[IPC_TOKEN_ENV];
+        delete process.env[IPC_SOCKET_ENV];
     }
 
     async start(): Promise<void> {
         return new Promise((resolve, reject) => {
-            this.conn = net.createConnection(this.socketPath, () => {
+            this.conn = net.createConnection(socketAddress(this.socketPath), () => {
+                sendHandshake(this.conn!, this.token);
                 this.readConn();
                 resolve();
             });
@@ -1,11 +1,54 @@
 import * as net from 'node:net';
-import * as readline from 'node:readline';
 import {AsyncQueue} from './asyncqueue.js';
 import type {CallMessage, CallResult, Message, ResponseMessage, Vals} from './protocol.js';
 import {MsgType} from './protocol.js';
+import {currentMetadata, type Metadata, runWithMetadata} from './metadata.js';
+import {
+    errorFromPayload,
+    errorToPayload,
+    InvalidArgumentError,
+    MessageTooLargeError,
+    MethodNotFoundError,
+} from './errors.js';
+import {LineReader, messageHeader, type OversizedLine} from './linereader.js';
+import {ChunkAssembler, DEFAULT_FRAME_SIZE, FrameWriter} from './framewriter.js';
 
 export interface IPCOptions {
     debugMessages?: boolean;
+    /** Send the stack of a failed incoming call to the caller. Intended for debugging. */
+    propagateStacks?: boolean;
+    /**
+     * How ParentIPC passes the socket address to the child: appended as --ipc-socket argument (default)
+     * or in KITTEN_IPC_SOCKET environment variable, e.g. for children with strict argument parsers.
+     */
+    socketHandoff?: 'args' | 'env';
+    /**
+     * Which internal errors break the connection. By default warnings, like a late response to a call
+     * which has timed out, are only logged; 'strict' treats them as fatal.
+     */
+    errorPolicy?: 'default' | 'strict';
+    /**
+     * Limit of incoming messages in bytes, 1 GB by default. Larger calls are answered with MessageTooLargeError
+     * and larger responses fail their calls with it, without breaking the connection.
+     */
+    maxMessageSize?: number;
+    /** How long ParentIPC waits for the child to connect and authenticate, 10 seconds by default. */
+    acceptTimeoutMs?: number;
+    /**
+     * Largest frame written in bytes, 1 MB by default. Larger messages are sent in chunks which take turns
+     * with other messages, so a big message does not hold up the connection. Negative disables chunking.
+     */
+    frameSize?: number;
+}
+
+const DEFAULT_MAX_MESSAGE_SIZE = 1 << 30;
+
+/** Fatal errors break the connection, warnings are stray or late messages. */
+export type ErrorSeverity = 'warning' | 'fatal';
+
+export interface CallOptions {
+    /** Sent alongside the call. Defaults to the current metadata, see withMetadata. */
+    metadata?: Metadata;
 }
 
 export abstract class IPCCommon {
@@ -18,6 +61,12 @@ export abstract class IPCCommon {
     protected processingCalls: number = 0;
     protected ready = false;
     protected debugMessages: boolean;
+    protected propagateStacks: boolean;
+    protected errorPolicy: 'default' | 'strict';
+    protected maxMessageSize: number;
+    protected frameSize: number;
+    protected frameWriter: FrameWriter | null = null;
+    protected chunks: ChunkAssembler;
 
     protected errorQueue = new AsyncQueue<Error>();
     protected onClose?: () => void;
@@ -25,6 +74,15 @@ export abstract class IPCCommon {
     protected constructor(localApis: object[], socketPath: string, opts?: IPCOptions) {
         this.socketPath = socketPath;
         this.debugMessages = opts?.debugMessages ?? false;
+        this.propagateStacks = opts?.propagateStacks ?? false;
+        this.errorPolicy = opts?.errorPolicy ?? 'default';
+        this.maxMessageSize = opts?.maxMessageSize ?? DEFAULT_MAX_MESSAGE_SIZE;
+        this.frameSize = opts?.frameSize || DEFAULT_FRAME_SIZE;
+        this.chunks = new ChunkAssembler(
+            this.maxMessageSize,
+            (line) => this.processLine(line),
+            (line) => this.rejectOversized(line),
+        );
 
         this.localApis = {};
         for (const localApi of localApis) {
@@ -35,10 +93,15 @@ export abstract class IPCCommon {
     protected readConn(): void {
         if (!this.conn) throw new Error('no connection');
 
-        const rl = readline.createInterface({
-            input: this.conn,
-            crlfDelay: Infinity,
-        });
+        const reader = new LineReader(
+            this.maxMessageSize,
+            (line) => this.processLine(line),
+            (line) => this.rejectOversized(line),
+        );
+        this.conn.on('data', (data: Buffer) => reader.push(data));
+        this.conn.on('end', () => reader.end());
+        // authenticate pauses the connection, and a data listener does not resume it
+        this.conn.resume();
 
         this.conn.on('error', (e) => {
             this.raiseErr(e);
@@ -51,19 +114,45 @@ export abstract class IPCCommon {
             }
         });
 
-        rl.on('line', (line) => {
+        this.ready = true;
+    }
+
+    protected processLine(line: string): void {
+        try {
+            if (this.debugMessages) {
+                console.log(`[ipc recv] ${line}`);
+            }
+            const msg: Message = JSON.parse(line);
+            this.processMsg(msg);
+        } catch (e) {
+            this.raiseErr(new Error(`${ e }`));
+        }
+    }
+
+    /** Answers a skipped call with MessageTooLargeError, or fails the call a skipped response belongs to. */
+    protected rejectOversized(line: OversizedLine): void {
+        const err = new MessageTooLargeError(
+            `message of ${ line.size } bytes exceeds limit of ${ this.maxMessageSize } bytes`,
+        );
+        const header = messageHeader(line.prefix);
+        if (header?.type === MsgType.Chunk) {
+            // the rest of the chunked message is skipped too, it is rejected after the last chunk
             try {
-                if (this.debugMessages) {
-                    console.log(`[ipc recv] ${line}`);
-                }
-                const msg: Message = JSON.parse(line);
-                this.processMsg(msg);
+                this.chunks.skip(header.id, line);
             } catch (e) {
-                this.raiseErr(new Error(`${ e }`));
+                this.raiseErr(new Error(`read chunk: ${ e }`));
             }
-        });
-
-        this.ready = true;
+            return;
+        }
+        if (header?.type === MsgType.Call) {
+            this.raiseErr(new Error(`rejected call id=${ header.id }: ${ err.message }`), 'warning');
+            this.sendMsg({type: MsgType.Response, id: header.id, error: errorToPayload(err)});
+        } else if (header?.type === MsgType.Response) {
+            this.raiseErr(new Error(`rejected response id=${ header.id }: ${ err.message }`), 'warning');
+            this.handleResponse({type: MsgType.Response, id: header.id, error: errorToPayload(err)});
+        } else {
+            this.raiseErr(new Error(`skipped message: ${ err.message }`), 'warning');
+        }
     }
 
     protected processMsg(msg: Message): void {
@@ -74,6 +163,9 @@ export abstract class IPCCommon {
             case MsgType.Response:
                 this.handleResponse(msg);
                 break;
+            case MsgType.Chunk:
+                this.chunks.add(msg);
+                break;
         }
     }
 
@@ -81,11 +173,12 @@ export abstract class IPCCommon {
         if (!this.conn) throw new Error('no connection');
 
         try {
-            const data = JSON.stringify(msg) + '\n';
+            const data = JSON.stringify(msg);
             if (this.debugMessages) {
-                console.log(`[ipc send] ${JSON.stringify(msg)}`);
+                console.log(`[ipc send] ${data}`);
             }
-            this.conn.write(data);
+            this.frameWriter ??= new FrameWriter(this.conn, this.frameSize);
+            this.frameWriter.write(data);
         } catch (e) {
             this.raiseErr(new Error(`send response for ${ msg.id }: ${ e }`));
         }
@@ -94,17 +187,29 @@ export abstract class IPCCommon {
     protected async handleCall(msg: CallMessage) {
         const [endpointName, methodName] = msg.method.split('.');
         if (!endpointName || !methodName) {
-            this.sendMsg({type: MsgType.Response, id: msg.id, error: `call malformed: ${ msg.method }`});
+            this.sendMsg({
+                type: MsgType.Response,
+                id: msg.id,
+                error: errorToPayload(new MethodNotFoundError(`call malformed: ${ msg.method }`)),
+            });
             return;
         }
         const endpoint = this.localApis[endpointName];
         if (!endpoint) {
-            this.sendMsg({type: MsgType.Response, id: msg.id, error: `endpoint not found: ${ endpointName }`});
+            this.sendMsg({
+                type: MsgType.Response,
+                id: msg.id,
+                error: errorToPayload(new MethodNotFoundError(`endpoint not found: ${ endpointName }`)),
+            });
             return;
         }
         const method: Function = endpoint[methodName];
         if (!method || typeof method !== 'function') {
-            this.sendMsg({type: MsgType.Response, id: msg.id, error: `method not found: ${ msg.method }`});
+            this.sendMsg({
+                type: MsgType.Response,
+                id: msg.id,
+                error: errorToPayload(new MethodNotFoundError(`method not found: ${ msg.method }`)),
+            });
             return;
         }
 
@@ -113,21 +218,26 @@ export abstract class IPCCommon {
             this.sendMsg({
                 type: MsgType.Response,
                 id: msg.id,
-                error: `argument count mismatch: expected ${ argsCount }, got ${ msg.args.length }`
+                error: errorToPayload(new InvalidArgumentError(
+                    `argument count mismatch: expected ${ argsCount }, got ${ msg.args.length }`,
+                )),
             });
             return;
         }
 
         try {
             this.processingCalls++;
-            let result = method.apply(endpoint, msg.args.map(this.deserialize));
+            let result = runWithMetadata(
+                msg.metadata ?? {},
+                () => method.apply(endpoint, msg.args.map(this.deserialize)),
+            );
             if (result instanceof Promise) {
                 result = await result;
             }
             result = this.serialize(result);
             this.sendMsg({type: MsgType.Response, id: msg.id, result: [result]});
         } catch (err) {
-            this.sendMsg({type: MsgType.Response, id: msg.id, error: `${ err }`});
+            this.sendMsg({type: MsgType.Response, id: msg.id, error: errorToPayload(err, this.propagateStacks)});
         } finally {
             this.processingCalls--;
         }
@@ -140,17 +250,26 @@ export abstract class IPCCommon {
     protected handleResponse(msg: ResponseMessage): void {
         const callback = this.pendingCalls[msg.id];
         if (!callback) {
-            this.raiseErr(new Error(`received response for unknown msgId: ${ msg.id }`));
+            // Usually a late response to a call which has timed out
+            this.raiseErr(new Error(`received response for unknown msgId: ${ msg.id }`), 'warning');
             return;
         }
 
         delete this.pendingCalls[msg.id];
 
-        const err = msg.error ? new Error(`remote error: ${ msg.error }`) : null;
+        if (this.debugMessages && msg.error?.stack) {
+            console.log(`[ipc remote stack] ${ msg.error.message }\n${ msg.error.stack }`);
+        }
+        const err = msg.error ? errorFromPayload(msg.error) : null;
         callback({result: msg.result || [], error: err});
     }
 
     call(method: string, ...args: Vals): Promise<Vals> {
+        return this.callWithOptions(method, {}, ...args);
+    }
+
+    callWithOptions(method: string, opts: CallOptions, ...args: Vals): Promise<Vals> {
+        const metadata = opts.metadata ?? currentMetadata();
         return new Promise((resolve, reject) => {
             const id = this.nextId++;
 
@@ -162,7 +281,11 @@ export abstract class IPCCommon {
                 }
             };
             try {
-                this.sendMsg({type: MsgType.Call, id, method, args: args.map(this.serialize)});
+                const msg: CallMessage = {type: MsgType.Call, id, method, args: args.map(this.serialize)};
+                if (Object.keys(metadata).length > 0) {
+                    msg.metadata = metadata;
+                }
+                this.sendMsg(msg);
             } catch (e) {
                 delete this.pendingCalls[id];
                 reject(new Error(`send call: ${ e }`));
@@ -237,7 +360,11 @@ export abstract class IPCCommon {
         }
     }
 
-    protected raiseErr(err: Error): void {
+    protected raiseErr(err: Error, severity: ErrorSeverity = 'fatal'): void {
+        if (severity === 'warning' && this.errorPolicy !== 'strict') {
+            console.warn(`ipc warning: ${ err.message }`);
+            return;
+        }
         this.errorQueue.put(err);
     }
 }
@@ -0,0 +1,63 @@
+import {test} from 'vitest';
+import {errorFromPayload, errorToPayload, InvalidArgumentError, IPCError, registerError} from './errors.js';
+
+class NotFoundError extends IPCError {
+    static override readonly code = 'NotFound';
+}
+registerError(NotFoundError);
+
+test('error to payload keeps code, details and cause', ({expect}) => {
+    const err = new NotFoundError('item not found', {details: {id: 42}, cause: new Error('db miss')});
+    expect(errorToPayload(err)).toEqual({
+        code: 'NotFound',
+        message: 'item not found',
+        details: {id: 42},
+        cause: {message: 'db miss'},
+    });
+});
+
+test('plain error has no code', ({expect}) => {
+    expect(errorToPayload(new Error('plain'))).toEqual({message: 'plain'});
+});
+
+test('payload maps to registered subclass', ({expect}) => {
+    const err = errorFromPayload({code: 'NotFound', message: 'item not found', cause: {message: 'db miss'}});
+    expect(err).toBeInstanceOf(NotFoundError);
+    expect(err.code).toBe('NotFound');
+    expect((err.cause as Error).message).toBe('db miss');
+});
+
+test('builtin codes are registered', ({expect}) => {
+    expect(errorFromPayload({code: 'InvalidArgument', message: 'bad'})).toBeInstanceOf(InvalidArgumentError);
+});
+
+test('unknown code keeps the code', ({expect}) => {
+    const err = errorFromPayload({code: 'Unknown', message: 'oops'});
+    expect(err.constructor).toBe(IPCError);
+    expect(err.code).toBe('Unknown');
+});
+
+test('stack is sent only when requested', ({expect}) => {
+    const err = new Error('crash');
+    expect(errorToPayload(err).stack).toBeUndefined();
+    expect(errorToPayload(err, true).stack).toBe(err.stack);
+});
+
+test('remote stack is exposed as cause', ({expect}) => {
+    const err = errorFromPayload({code: 'Internal', message: 'panic', stack: 'goroutine 1 [running]:'});
+    expect(err.remoteStack).toBe('goroutine 1 [running]:');
+    expect((err.cause as Error).stack).toBe('goroutine 1 [running]:');
+});
+
+test('several causes', ({expect}) => {
+    const err = new AggregateError([new Error('plain'), new NotFoundError('item not found')], 'failed');
+    const payload = errorToPayload(err);
+    expect(payload).toEqual({
+        message: 'failed',
+        causes: [{message: 'plain'}, {code: 'NotFound', message: 'item not found'}],
+    });
+
+    const cause = errorFromPayload(payload).cause as AggregateError;
+    expect(cause).toBeInstanceOf(AggregateError);
+    expect(cause.errors[1]).toBeInstanceOf(NotFoundError);
+});
@@ -0,0 +1,96 @@
+import type {ErrorPayload} from './protocol.js';
+
+const MAX_CAUSE_DEPTH = 16;
+
+export interface IPCErrorOptions {
+    details?: Record<string, unknown> | undefined;
+    cause?: unknown;
+}
+
+/**
+ * Error with a stable code which is preserved across the process boundary.
+ * Subclasses annotated with `@kittenipc error` are generated by kitcom for the remote side.
+ */
+export class IPCError extends Error {
+    static readonly code: string = '';
+
+    code: string;
+    details: Record<string, unknown> | undefined;
+    /** Stack of the failed call, sent by the remote process when stack propagation is enabled there. */
+    remoteStack: string | undefined;
+
+    constructor(message?: string, opts?: IPCErrorOptions) {
+        super(message, opts?.cause !== undefined ? {cause: opts.cause} : undefined);
+        this.name = new.target.name;
+        this.code = (new.target as typeof IPCError).code;
+        this.details = opts?.details;
+        this.remoteStack = undefined;
+    }
+}
+
+export class MethodNotFoundError extends IPCError {
+    static override readonly code = 'MethodNotFound';
+}
+
+export class InvalidArgumentError extends IPCError {
+    static override readonly code = 'InvalidArgument';
+}
+
+export class InternalError extends IPCError {
+    static override readonly code = 'Internal';
+}
+
+/** Returned for calls and responses over the maxMessageSize option of the receiving side. */
+export class MessageTooLargeError extends IPCError {
+    static override readonly code = 'MessageTooLarge';
+}
+
+const registry = new Map<string, typeof IPCError>();
+
+export function registerError(errorClass: typeof IPCError): void {
+    registry.set(errorClass.code, errorClass);
+}
+
+registerError(MethodNotFoundError);
+registerError(InvalidArgumentError);
+registerError(InternalError);
+registerError(MessageTooLargeError);
+
+export function errorFromPayload(payload: ErrorPayload): IPCError {
+    const errorClass = (payload.code && registry.get(payload.code)) || IPCError;
+    let cause: unknown = payload.cause ? errorFromPayload(payload.cause) : undefined;
+    if (payload.causes) {
+        cause = new AggregateError(payload.causes.map((c) => errorFromPayload(c)), payload.message);
+    }
+    if (payload.stack) {
+        // remote stack is exposed as the cause, so it is printed along with the error
+        const stackErr = new Error(payload.message, cause !== undefined ? {cause} : undefined);
+        stackErr.name = 'RemoteStack';
+        stackErr.stack = payload.stack;
+        cause = stackErr;
+    }
+    const err = new errorClass(payload.message, {details: payload.details, cause});
+    err.code = payload.code ?? '';
+    err.remoteStack = payload.stack;
+    return err;
+}
+
+export function errorToPayload(err: unknown, withStack: boolean = false, depth: number = 0): ErrorPayload {
+    const payload: ErrorPayload = {message: err instanceof Error ? err.message : `${ err }`};
+    if (withStack && err instanceof Error && err.stack) {
+        payload.stack = err.stack;
+    }
+    if (err instanceof IPCError) {
+        if (err.code) payload.code = err.code;
+        if (err.details) payload.details = err.details;
+    }
+    if (depth >= MAX_CAUSE_DEPTH) {
+        return payload;
+    }
+    if (err instanceof AggregateError) {
+        payload.causes = err.errors.map((e) => errorToPayload(e, false, depth + 1));
+    } else if (err instanceof Error && err.cause !== undefined) {
+        payload.cause = errorToPayload(err.cause, false, depth + 1);
+    }
+    return payload;
+}
@@ -0,0 +1,95 @@
+import {EventEmitter} from 'node:events';
+import type * as net from 'node:net';
+import {test} from 'vitest';
+import {ChunkAssembler, FrameWriter} from './framewriter.js';
+import type {OversizedLine} from './linereader.js';
+import {MsgType} from './protocol.js';
+
+/** Records written frames. The first write reports a full buffer until drain is emitted. */
+class FakeSocket extends EventEmitter {
+    frames: string[] = [];
+    destroyed = false;
+
+    write(data: Buffer): boolean {
+        this.frames.push(data.toString('utf8'));
+        return this.frames.length > 1;
+    }
+}
+
+function assemble(frames: string[]): string[] {
+    const out: string[] = [];
+    const chunks = new ChunkAssembler(1 << 30, (line) => out.push(line), () => {});
+    for (const frame of frames) {
+        const msg = JSON.parse(frame);
+        if (msg.type === MsgType.Chunk) {
+            chunks.add(msg);
+        } else {
+            out.push(frame.slice(0, -1));
+        }
+    }
+    return out;
+}
+
+const big = `{"type":2,"id":1,"result":["${ 'x'.repeat(2000) }"]}`;
+const small = '{"type":1,"id":2,"method":"A.B"}';
+
+test('chunks interleave', ({expect}) => {
+    const conn = new FakeSocket();
+    const writer = new FrameWriter(conn as unknown as net.Socket, 256);
+    writer.write(big);
+    writer.write(small);
+    conn.emit('drain');
+
+    expect(conn.frames.length).toBeGreaterThan(2);
+    for (const frame of conn.frames) {
+        expect(Buffer.byteLength(frame) - 1).toBeLessThan(257);
+    }
+    expect(conn.frames[1]).toBe(small + '\n');
+    expect(assemble(conn.frames)).toEqual([small, big]);
+});
+
+test('chunking disabled', ({expect}) => {
+    const conn = new FakeSocket();
+    const writer = new FrameWriter(conn as unknown as net.Socket, -1);
+    writer.write(big);
+    expect(conn.frames).toEqual([big + '\n']);
+});
+
+test('reassembled message over limit', ({expect}) => {
+    const out: (string | OversizedLine)[] = [];
+    const chunks = new ChunkAssembler(12, (line) => out.push(line), (line) => out.push(line));
+    const data = (s: string) => Buffer.from(s).toString('base64');
+    chunks.add({type: MsgType.Chunk, id: 1, data: data('{"type":1,')});
+    chunks.add({type: MsgType.Chunk, id: 2, data: data('{}'), last: true});
+    chunks.add({type: MsgType.Chunk, id: 1, data: data('"id":5}'), last: true});
+    expect(out).toEqual(['{}', {size: 17, prefix: '{"type":1,"id":5}', suffix: ''}]);
+});
+
+test('reassembled messages share limit', ({expect}) => {
+    const out: (string | OversizedLine)[] = [];
+    const chunks = new ChunkAssembler(10, (line) => out.push(line), (line) => out.push(line));
+    const data = (s: string) => Buffer.from(s).toString('base64');
+    chunks.add({type: MsgType.Chunk, id: 1, data: data('{"a":')});
+    chunks.add({type: MsgType.Chunk, id: 2, data: data('{"b":2')});
+    chunks.add({type: MsgType.Chunk, id: 2, data: data('}'), last: true});
+    chunks.add({type: MsgType.Chunk, id: 1, data: data('1}'), last: true});
+    expect(out).toEqual([{size: 7, prefix: '{"b":2}', suffix: ''}, '{"a":1}']);
+});
+
+test('skipped chunk frames', ({expect}) => {
+    const out: (string | OversizedLine)[] = [];
+    const chunks = new ChunkAssembler(100, (line) => out.push(line), (line) => out.push(line));
+    const first = JSON.stringify({type: MsgType.Chunk, id: 1, data: Buffer.from('{"type":1,"id":9,').toString('base64')});
+    chunks.skip(1, {size: 200, prefix: first, suffix: first.slice(-16)});
+    const last = JSON.stringify({type: MsgType.Chunk, id: 1, data: 'eHh4', last: true});
+    chunks.skip(1, {size: 300, prefix: last, suffix: last.slice(-16)});
+    expect(out).toEqual([{size: 500, prefix: '{"type":1,"id":9,xxx', suffix: ''}]);
+});
+
+test('too many chunked messages', ({expect}) => {
+    const chunks = new ChunkAssembler(1 << 20, () => {}, () => {});
+    for (let id = 1; id <= 1024; id++) {
+        chunks.add({type: MsgType.Chunk, id, data: 'eA=='});
+    }
+    expect(() => chunks.add({type: MsgType.Chunk, id: 1025, data: 'eA=='})).toThrow('in progress');
+});
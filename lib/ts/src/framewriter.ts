@@ -0,0 +1,195 @@
+import type * as net from 'node:net';
+import {MsgType} from './protocol.js';
+import type {ChunkMessage} from './protocol.js';
+import {OVERSIZED_PREFIX} from './linereader.js';
+import type {OversizedLine} from './linereader.js';
+
+export const DEFAULT_FRAME_SIZE = 1 << 20;
+const MIN_FRAME_SIZE = 256;
+/** Upper bound of the chunk frame size without its data. */
+const CHUNK_OVERHEAD = 64;
+
+interface OutMessage {
+    data: Buffer;
+    /** Zero for messages sent whole. */
+    chunkId: number;
+    off: number;
+}
+
+/**
+ * Writes messages in frames of at most frameSize bytes. Frames of queued messages take turns,
+ * so small messages are not stuck behind big ones. Writing pauses while the socket buffer is full.
+ */
+export class FrameWriter {
+    private readonly frameSize: number;
+    private queue: OutMessage[] = [];
+    private nextChunkId = 0;
+    /** Partly written message whose frame is waiting for drain. */
+    private unflushed: OutMessage | null = null;
+    private waitingDrain = false;
+
+    /** frameSize of zero or less disables chunking. */
+    constructor(private readonly conn: net.Socket, frameSize: number) {
+        this.frameSize = frameSize > 0 ? Math.max(frameSize, MIN_FRAME_SIZE) : 0;
+        conn.on('drain', () => {
+            this.waitingDrain = false;
+            if (this.unflushed) {
+                this.queue.push(this.unflushed);
+                this.unflushed = null;
+            }
+            this.pump();
+        });
+    }
+
+    /** Queues an encoded message, without the trailing newline. */
+    write(line: string): void {
+        const data = Buffer.from(line, 'utf8');
+        const msg: OutMessage = {data, chunkId: 0, off: 0};
+        if (this.frameSize > 0 && data.length > this.frameSize) {
+            msg.chunkId = ++this.nextChunkId;
+        }
+        this.queue.push(msg);
+        this.pump();
+    }
+
+    private pump(): void {
+        while (this.queue.length > 0 && !this.waitingDrain && !this.conn.destroyed) {
+            const msg = this.queue.shift()!;
+            const [frame, last] = this.nextFrame(msg);
+            if (this.conn.write(frame)) {
+                if (!last) this.queue.push(msg);
+                continue;
+            }
+            this.waitingDrain = true;
+            // requeued on drain, behind messages queued in the meantime
+            if (!last) this.unflushed = msg;
+        }
+    }
+
+    private nextFrame(msg: OutMessage): [Buffer, boolean] {
+        if (msg.chunkId === 0) {
+            return [Buffer.concat([msg.data, Buffer.from('\n')]), true];
+        }
+        const chunkSize = Math.floor((this.frameSize - CHUNK_OVERHEAD) / 4) * 3;
+        const end = Math.min(msg.off + chunkSize, msg.data.length);
+        const chunk: ChunkMessage = {
+            type: MsgType.Chunk,
+            id: msg.chunkId,
+            data: msg.data.subarray(msg.off, end).toString('base64'),
+        };
+        if (end === msg.data.length) {
+            chunk.last = true;
+        }
+        msg.off = end;
+        return [Buffer.from(JSON.stringify(chunk) + '\n', 'utf8'), chunk.last === true];
+    }
+}
+
+interface ChunkedMessage {
+    parts: Buffer[];
+    length: number;
+    oversized: { size: number, prefix: Buffer } | null;
+}
+
+/**
+ * Limits chunked messages being assembled at once. Writers send chunks of queued messages in turns,
+ * so it takes this many concurrent big messages to reach it.
+ */
+const MAX_CHUNKED_MESSAGES = 1024;
+
+function appendPrefix(prefix: Buffer, data: Buffer): Buffer {
+    const missing = OVERSIZED_PREFIX - prefix.length;
+    return missing > 0 ? Buffer.concat([prefix, data.subarray(0, missing)]) : prefix;
+}
+
+/** Decodes what it can of the data of a chunk frame from its beginning. */
+function chunkDataPrefix(framePrefix: string): Buffer {
+    const start = framePrefix.indexOf('"data":"');
+    if (start < 0) return Buffer.alloc(0);
+    let encoded = framePrefix.slice(start + '"data":"'.length);
+    const end = encoded.indexOf('"');
+    if (end >= 0) encoded = encoded.slice(0, end);
+    return Buffer.from(encoded.slice(0, Math.floor(encoded.length / 4) * 4), 'base64');
+}
+
+/**
+ * Puts chunked messages together, skipping those over the message size limit.
+ * Messages being assembled share the limit, so a peer which never finishes them cannot exceed it.
+ */
+export class ChunkAssembler {
+    private messages = new Map<number, ChunkedMessage>();
+    /** Bytes of messages being assembled. */
+    private total = 0;
+
+    constructor(
+        private readonly maxLength: number,
+        private readonly onMessage: (line: string) => void,
+        private readonly onOversized: (line: OversizedLine) => void,
+    ) {}
+
+    /** Throws if too many messages are in progress. */
+    add(chunk: ChunkMessage): void {
+        const data = Buffer.from(chunk.data, 'base64');
+        const msg = this.message(chunk.id);
+        if (msg.oversized) {
+            msg.oversized.size += data.length;
+            msg.oversized.prefix = appendPrefix(msg.oversized.prefix, data);
+        } else if (msg.length + data.length > this.maxLength || this.total + data.length > this.maxLength) {
+            this.setOversized(msg);
+            msg.oversized!.size += data.length;
+            msg.oversized!.prefix = appendPrefix(msg.oversized!.prefix, data);
+        } else {
+            msg.parts.push(data);
+            msg.length += data.length;
+            this.total += data.length;
+        }
+        if (!chunk.last) return;
+
+        this.messages.delete(chunk.id);
+        this.total -= msg.length;
+        if (msg.oversized) {
+            this.onOversized({size: msg.oversized.size, prefix: msg.oversized.prefix.toString('utf8'), suffix: ''});
+        } else {
+            this.onMessage(Buffer.concat(msg.parts, msg.length).toString('utf8'));
+        }
+    }
+
+    /**
+     * Marks the message of a chunk frame which was too large to read as oversized, keeping the beginning
+     * of its data if it is the first chunk. Throws if too many messages are in progress.
+     */
+    skip(id: number, skipped: OversizedLine): void {
+        const msg = this.message(id);
+        if (!msg.oversized) {
+            this.setOversized(msg);
+        }
+        msg.oversized!.size += skipped.size;
+        msg.oversized!.prefix = appendPrefix(msg.oversized!.prefix, chunkDataPrefix(skipped.prefix));
+        // writers put the last flag at the end of the frame
+        if (!skipped.suffix.endsWith('"last":true}')) return;
+
+        this.messages.delete(id);
+        this.onOversized({size: msg.oversized!.size, prefix: msg.oversized!.prefix.toString('utf8'), suffix: ''});
+    }
+
+    private message(id: number): ChunkedMessage {
+        let msg = this.messages.get(id);
+        if (!msg) {
+            if (this.messages.size >= MAX_CHUNKED_MESSAGES) {
+                throw new Error(`more than ${ MAX_CHUNKED_MESSAGES } chunked messages in progress`);
+            }
+            msg = {parts: [], length: 0, oversized: null};
+            this.messages.set(id, msg);
+        }
+        return msg;
+    }
+
+    /** Drops data collected for msg, keeping its beginning. */
+    private setOversized(msg: ChunkedMessage): void {
+        const data = Buffer.concat(msg.parts, msg.length);
+        msg.oversized = {size: msg.length, prefix: data.subarray(0, OVERSIZED_PREFIX)};
+        this.total -= msg.length;
+        msg.parts = [];
+        msg.length = 0;
+    }
+}
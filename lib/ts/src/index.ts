@@ -1,3 +1,7 @@
 export {ParentIPC} from './parent.js';
 export {ChildIPC} from './child.js';
-export type {IPCOptions} from './common.js';
+export type {IPCOptions, CallOptions, ErrorSeverity} from './common.js';
+export {withMetadata, currentMetadata} from './metadata.js';
+export type {Metadata} from './metadata.js';
+export {IPCError, MethodNotFoundError, InvalidArgumentError, InternalError, MessageTooLargeError, registerError} from './errors.js';
+export type {IPCErrorOptions} from './errors.js';
@@ -10,3 +10,10 @@ test('test process stop before connection accept', async ({expect}) => {
     const parentIpc = new ParentIPC('../testdata/sleep3.sh', []);
     await expect(parentIpc.start()).rejects.toThrowError();
 }, 15000);
+
+test('accept timeout option', async ({expect}) => {
+    const parentIpc = new ParentIPC('../testdata/sleep15.sh', [], {acceptTimeoutMs: 500});
+    const start = Date.now();
+    await expect(parentIpc.start()).rejects.toThrowError('timed out');
+    expect(Date.now() - start).toBeLessThan(5000);
+}, 15000);
@@ -0,0 +1,34 @@
+import {test} from 'vitest';
+import {LineReader, messageHeader, type OversizedLine} from './linereader.js';
+
+function read(maxLength: number, ...chunks: string[]): (string | OversizedLine)[] {
+    const out: (string | OversizedLine)[] = [];
+    const reader = new LineReader(maxLength, (line) => out.push(line), (line) => out.push(line));
+    for (const chunk of chunks) {
+        reader.push(Buffer.from(chunk));
+    }
+    reader.end();
+    return out;
+}
+
+test('lines split across chunks', ({expect}) => {
+    expect(read(100, 'fir', 'st\r\nsec', 'ond\nthird')).toEqual(['first', 'second', 'third']);
+});
+
+test('oversized lines skipped', ({expect}) => {
+    const big = `{"type":1,"id":7,"args":["${ 'x'.repeat(300) }"]}`;
+    const out = read(10, 'short\n', big.slice(0, 100), big.slice(100) + '\nafter\n');
+    expect(out).toHaveLength(3);
+    expect(out[0]).toBe('short');
+    expect((out[1] as OversizedLine).size).toBe(big.length);
+    expect((out[1] as OversizedLine).prefix).toHaveLength(256);
+    expect((out[1] as OversizedLine).suffix).toBe(big.slice(-16));
+    expect(out[2]).toBe('after');
+});
+
+test('message header', ({expect}) => {
+    expect(messageHeader('{"type":1,"id":42,"method":"A.B","args":["xx')).toEqual({type: 1, id: 42});
+    expect(messageHeader('{ "id": 3, "type": 2, "result": [')).toEqual({type: 2, id: 3});
+    expect(messageHeader('{"type":1,"args":["x"],"id":3}')).toBeNull();
+    expect(messageHeader('garbage')).toBeNull();
+});
@@ -0,0 +1,97 @@
+/** How much of a skipped line is kept to find out the type and id of the message. */
+export const OVERSIZED_PREFIX = 256;
+/** How much of the end of a skipped line is kept, enough for the last flag of a chunk. */
+const OVERSIZED_SUFFIX = 16;
+
+export interface OversizedLine {
+    size: number;
+    prefix: string;
+    suffix: string;
+}
+
+function appendSuffix(suffix: Buffer, data: Buffer): Buffer {
+    const joined = Buffer.concat([suffix, data.subarray(Math.max(0, data.length - OVERSIZED_SUFFIX))]);
+    return joined.subarray(Math.max(0, joined.length - OVERSIZED_SUFFIX));
+}
+
+/**
+ * Splits incoming data into lines. Unlike readline it skips lines over the limit instead of buffering them,
+ * so a single huge message does not break the connection.
+ */
+export class LineReader {
+    private chunks: Buffer[] = [];
+    private length = 0;
+    private oversized: { size: number, prefix: Buffer, suffix: Buffer } | null = null;
+
+    constructor(
+        private readonly maxLength: number,
+        private readonly onLine: (line: string) => void,
+        private readonly onOversized: (line: OversizedLine) => void,
+    ) {}
+
+    push(data: Buffer): void {
+        let start = 0;
+        while (start < data.length) {
+            const nl = data.indexOf(0x0a, start);
+            this.append(data.subarray(start, nl < 0 ? data.length : nl));
+            if (nl < 0) break;
+            this.finish();
+            start = nl + 1;
+        }
+    }
+
+    /** Emits the last line if it has no newline. */
+    end(): void {
+        if (this.length > 0 || this.oversized) {
+            this.finish();
+        }
+    }
+
+    private append(part: Buffer): void {
+        if (this.oversized) {
+            this.oversized.size += part.length;
+            const missing = OVERSIZED_PREFIX - this.oversized.prefix.length;
+            if (missing > 0) {
+                this.oversized.prefix = Buffer.concat([this.oversized.prefix, part.subarray(0, missing)]);
+            }
+            this.oversized.suffix = appendSuffix(this.oversized.suffix, part);
+            return;
+        }
+        this.chunks.push(part);
+        this.length += part.length;
+        if (this.length > this.maxLength) {
+            const data = Buffer.concat(this.chunks, this.length);
+            this.oversized = {
+                size: this.length,
+                prefix: data.subarray(0, OVERSIZED_PREFIX),
+                suffix: appendSuffix(Buffer.alloc(0), data),
+            };
+            this.chunks = [];
+            this.length = 0;
+        }
+    }
+
+    private finish(): void {
+        if (this.oversized) {
+            const {size, prefix, suffix} = this.oversized;
+            this.oversized = null;
+            this.onOversized({size, prefix: prefix.toString('utf8'), suffix: suffix.toString('utf8').replace(/\r$/, '')});
+            return;
+        }
+        let line = Buffer.concat(this.chunks, this.length).toString('utf8');
+        this.chunks = [];
+        this.length = 0;
+        if (line.endsWith('\r')) {
+            line = line.slice(0, -1);
+        }
+        this.onLine(line);
+    }
+}
+
+/** Parses type and id from the beginning of a message. Both sides write them first. */
+export function messageHeader(prefix: string): { type: number, id: number } | null {
+    const type = /^\s*\{[^{}\[\]]*?"type"\s*:\s*(\d+)/.exec(prefix);
+    const id = /^\s*\{[^{}\[\]]*?"id"\s*:\s*(\d+)/.exec(prefix);
+    if (!type || !id) return null;
+    return {type: Number(type[1]), id: Number(id[1])};
+}
@@ -0,0 +1,16 @@
+import {test} from 'vitest';
+import {currentMetadata, withMetadata} from './metadata.js';
+
+test('metadata is empty outside of scope', ({expect}) => {
+    expect(currentMetadata()).toEqual({});
+});
+
+test('nested scopes merge metadata', async ({expect}) => {
+    await withMetadata({a: '1', b: '2'}, async () => {
+        await withMetadata({b: '3'}, async () => {
+            await Promise.resolve();
+            expect(currentMetadata()).toEqual({a: '1', b: '3'});
+        });
+        expect(currentMetadata()).toEqual({a: '1', b: '2'});
+    });
+});
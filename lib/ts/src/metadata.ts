@@ -0,0 +1,23 @@
+import {AsyncLocalStorage} from 'node:async_hooks';
+
+/** Sent alongside call arguments, e.g. request ids, auth tokens or trace ids. */
+export type Metadata = Record<string, string>;
+
+const storage = new AsyncLocalStorage<Metadata>();
+
+/**
+ * Runs fn with metadata merged over the current one.
+ * Calls made inside fn, including calls of generated APIs, send this metadata.
+ */
+export function withMetadata<T>(metadata: Metadata, fn: () => T): T {
+    return storage.run({...currentMetadata(), ...metadata}, fn);
+}
+
+/** Returns metadata set by withMetadata, or metadata sent by the caller when called inside an incoming call. */
+export function currentMetadata(): Metadata {
+    return storage.getStore() ?? {};
+}
+
+export function runWithMetadata<T>(metadata: Metadata, fn: () => T): T {
+    return storage.run(metadata, fn);
+}
@@ -5,25 +5,34 @@ import * as fs from 'node:fs';
 import * as crypto from 'node:crypto';
 import {type ChildProcess, spawn} from 'node:child_process';
 import {IPCCommon, type IPCOptions} from './common.js';
-import {timeout} from './util.js';
+import {IPC_SOCKET_ARG, IPC_SOCKET_ENV, timeout} from './util.js';
+import {authenticate, IPC_TOKEN_ENV, newToken} from './auth.js';
 
-const IPC_SOCKET_ARG = 'ipc-socket';
-const ACCEPT_TIMEOUT_MS = 10000;
+const DEFAULT_ACCEPT_TIMEOUT_MS = 10000;
 
 export class ParentIPC extends IPCCommon {
     private readonly cmdPath: string;
     private readonly cmdArgs: string[];
+    private readonly socketDir: string;
+    private readonly token: string;
+    private readonly socketHandoff: 'args' | 'env';
+    private readonly acceptTimeoutMs: number;
     private cmd: ChildProcess | null = null;
     private readonly listener: net.Server;
     private cmdExitResult: { code: number | null, signal: string | null } | null = null;
     private cmdExitCallbacks: ((result: { code: number | null, signal: string | null }) => void)[] = [];
 
     constructor(cmdPath: string, cmdArgs: string[], opts?: IPCOptions, ...localApis: object[]) {
-        const socketPath = path.join(os.tmpdir(), `kitten-ipc-${ process.pid }-${ crypto.randomInt(2**48 - 1) }.sock`);
-        super(localApis, socketPath, opts);
+        // the directory is created by start and fails if it already exists, so nobody else can own it
+        const socketDir = path.join(os.tmpdir(), `kitten-ipc-${ process.pid }-${ crypto.randomBytes(8).toString('hex') }`);
+        super(localApis, path.join(socketDir, 'ipc.sock'), opts);
+        this.socketDir = socketDir;
+        this.token = newToken();
 
         this.cmdPath = cmdPath;
-        if (cmdArgs.includes(`--${ IPC_SOCKET_ARG }`)) {
+        this.socketHandoff = opts?.socketHandoff ?? 'args';
+        this.acceptTimeoutMs = opts?.acceptTimeoutMs ?? DEFAULT_ACCEPT_TIMEOUT_MS;
+        if (this.socketHandoff === 'args' && cmdArgs.includes(`--${ IPC_SOCKET_ARG }`)) {
             throw new Error(`you should not use '--${ IPC_SOCKET_ARG }' argument in your command`);
         }
         this.cmdArgs = cmdArgs;
@@ -32,20 +41,28 @@ export class ParentIPC extends IPCCommon {
     }
 
     async start(): Promise<void> {
+        fs.mkdirSync(this.socketDir, {mode: 0o700});
         try {
-            fs.unlinkSync(this.socketPath);
-        } catch {
-        }
-
-        await new Promise<void>((resolve, reject) => {
-            this.listener.listen(this.socketPath, () => {
-                resolve();
+            await new Promise<void>((resolve, reject) => {
+                this.listener.listen(this.socketPath, () => {
+                    resolve();
+                });
+                this.listener.on('error', reject);
             });
-            this.listener.on('error', reject);
-        });
+            fs.chmodSync(this.socketPath, 0o600);
+        } catch (e) {
+            this.removeSocketDir();
+            throw e;
+        }
 
-        const cmdArgs = [...this.cmdArgs, `--${ IPC_SOCKET_ARG }`, this.socketPath];
-        this.cmd = spawn(this.cmdPath, cmdArgs, {stdio: 'inherit'});
+        const cmdArgs = [...this.cmdArgs];
+        const env: NodeJS.ProcessEnv = {...process.env, [IPC_TOKEN_ENV]: this.token};
+        if (this.socketHandoff === 'env') {
+            env[IPC_SOCKET_ENV] = this.socketPath;
+        } else {
+            cmdArgs.push(`--${ IPC_SOCKET_ARG }`, this.socketPath);
+        }
+        this.cmd = spawn(this.cmdPath, cmdArgs, {stdio: 'inherit', env});
 
         this.cmd.on('error', (err) => {
             this.raiseErr(err);
@@ -61,10 +78,25 @@ export class ParentIPC extends IPCCommon {
         await this.acceptConn();
     }
 
+    /**
+     * Waits for the child to connect and authenticate. Connections failing authentication
+     * are closed while waiting goes on, so they cannot take the place of the child.
+     */
     private async acceptConn(): Promise<void> {
+        let accepted = false;
         const acceptPromise = new Promise<net.Socket>((resolve, reject) => {
-            this.listener.once('connection', (conn) => {
-                resolve(conn);
+            this.listener.on('connection', (conn) => {
+                authenticate(conn, this.token, this.acceptTimeoutMs).then(() => {
+                    if (accepted) {
+                        conn.destroy();
+                        return;
+                    }
+                    accepted = true;
+                    resolve(conn);
+                }, (err) => {
+                    console.warn(`[ipc] connection rejected: ${ err.message }`);
+                    conn.destroy();
+                });
             });
             this.listener.once('error', reject);
         });
@@ -80,14 +112,22 @@ export class ParentIPC extends IPCCommon {
         });
 
         try {
-            this.conn = await timeout(Promise.race([acceptPromise, exitPromise]), ACCEPT_TIMEOUT_MS);
+            this.conn = await timeout(Promise.race([acceptPromise, exitPromise]), this.acceptTimeoutMs);
             this.readConn();
         } catch (e) {
             if (this.cmd) this.cmd.kill();
+            this.removeSocketDir();
             throw e;
+        } finally {
+            accepted = true;
+            this.listener.close();
         }
     }
 
+    private removeSocketDir(): void {
+        try { fs.rmSync(this.socketDir, {recursive: true, force: true}); } catch {}
+    }
+
     async wait(): Promise<void> {
         if (!this.cmd) {
             throw new Error('Command is not started yet');
@@ -120,7 +160,7 @@ export class ParentIPC extends IPCCommon {
                 }),
             ]);
         } finally {
-            try { fs.unlinkSync(this.socketPath); } catch {}
+            this.removeSocketDir();
         }
     }
 }
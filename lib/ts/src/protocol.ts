@@ -1,6 +1,12 @@
+import type {Metadata} from './metadata.js';
+
 export enum MsgType {
     Call = 1,
     Response = 2,
+    /** First message of the child, authenticating it with the token */
+    Handshake = 3,
+    /** Part of a message larger than the frame size */
+    Chunk = 4,
 }
 
 export type Vals = any[];
@@ -10,16 +16,44 @@ export interface CallMessage {
     id: number,
     method: string;
     args: Vals;
+    metadata?: Metadata;
+}
+
+export interface ErrorPayload {
+    code?: string;
+    message: string;
+    details?: Record<string, unknown>;
+    cause?: ErrorPayload;
+    /** Set instead of cause for errors wrapping several errors, like AggregateError. */
+    causes?: ErrorPayload[];
+    stack?: string;
 }
 
 export interface ResponseMessage {
     type: MsgType.Response,
     id: number,
     result?: Vals;
-    error?: string;
+    error?: ErrorPayload;
+}
+
+export interface HandshakeMessage {
+    type: MsgType.Handshake,
+    token: string;
+}
+
+/**
+ * Chunks of a message share the id, which is unrelated to call ids, and arrive in order.
+ * The last one completes the message.
+ */
+export interface ChunkMessage {
+    type: MsgType.Chunk,
+    id: number,
+    /** Base64 encoded part of the message */
+    data: string;
+    last?: boolean;
 }
 
-export type Message = CallMessage | ResponseMessage;
+export type Message = CallMessage | ResponseMessage | ChunkMessage;
 
 export interface CallResult {
     result: Vals;
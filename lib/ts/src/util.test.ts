@@ -0,0 +1,22 @@
+import {test} from 'vitest';
+import {socketAddress, socketPathFromEnvOrArgs} from './util.js';
+
+test('socket path from args', ({expect}) => {
+    expect(socketPathFromEnvOrArgs({}, ['node', 'child.js', '--strict-flag', '--ipc-socket', '/tmp/a.sock']))
+        .toBe('/tmp/a.sock');
+    expect(socketPathFromEnvOrArgs({}, ['node', 'child.js', '--ipc-socket=/tmp/a.sock'])).toBe('/tmp/a.sock');
+});
+
+test('socket path from env takes precedence', ({expect}) => {
+    expect(socketPathFromEnvOrArgs({KITTEN_IPC_SOCKET: '/tmp/b.sock'}, ['node', '--ipc-socket', '/tmp/a.sock']))
+        .toBe('/tmp/b.sock');
+});
+
+test('socket path missing', ({expect}) => {
+    expect(() => socketPathFromEnvOrArgs({}, ['node', 'child.js', '--ipc-socket'])).toThrowError('missing');
+});
+
+test('abstract socket address', ({expect}) => {
+    expect(socketAddress('@kitten-ipc-1')).toBe('\0kitten-ipc-1');
+    expect(socketAddress('/tmp/a.sock')).toBe('/tmp/a.sock');
+});
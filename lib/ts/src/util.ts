@@ -1,21 +1,32 @@
-import * as util from 'node:util';
+export const IPC_SOCKET_ARG = 'ipc-socket';
+export const IPC_SOCKET_ENV = 'KITTEN_IPC_SOCKET';
 
-const IPC_SOCKET_ARG = 'ipc-socket';
+/**
+ * Takes the socket path from KITTEN_IPC_SOCKET or else from --ipc-socket.
+ * Arguments are scanned rather than parsed, so the host application may have any arguments of its own.
+ */
+export function socketPathFromEnvOrArgs(env = process.env, argv = process.argv): string {
+    const fromEnv = env[IPC_SOCKET_ENV];
+    if (fromEnv) {
+        return fromEnv;
+    }
 
-export function socketPathFromArgs(): string {
-    const {values} = util.parseArgs({
-        options: {
-            [IPC_SOCKET_ARG]: {
-                type: 'string',
-            }
+    for (let i = 0; i < argv.length; i++) {
+        const arg = argv[i]!;
+        if (arg === `--${ IPC_SOCKET_ARG }` && i + 1 < argv.length) {
+            return argv[i + 1]!;
+        }
+        if (arg.startsWith(`--${ IPC_SOCKET_ARG }=`)) {
+            return arg.slice(IPC_SOCKET_ARG.length + 3);
         }
-    });
-
-    if (!values[IPC_SOCKET_ARG]) {
-        throw new Error('ipc socket path is missing');
     }
 
-    return values[IPC_SOCKET_ARG];
+    throw new Error('ipc socket path is missing');
+}
+
+/** Abstract unix socket addresses (Linux) are passed with a leading '@', node expects a NUL byte instead. */
+export function socketAddress(socketPath: string): string {
+    return socketPath.startsWith('@') ? '\0' + socketPath.slice(1) : socketPath;
 }
 
 export function timeout<T>(prom: Promise<T>, ms: number): Promise<T> {